@@ -0,0 +1,4 @@
+// Package authv1 contains the generated code of the auth.v1 gRPC API.
+package authv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/auth/v1/auth.proto
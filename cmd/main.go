@@ -2,19 +2,34 @@ package main
 
 import (
 	"authservice/internal/app"
+	"authservice/internal/config"
 	"context"
+	"flag"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
 
-	ctx := context.Background()
-	app, err := app.NewApp(ctx)
+	configPath := flag.String("config", "", "path to the YAML config file, CONFIG_FILE by default")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app, err := app.NewApp(ctx, cfg)
 	if err != nil {
 		slog.Error("Failed to create application", "error", err)
 		os.Exit(1)
@@ -31,14 +46,12 @@ func main() {
 		}
 	}()
 
-	port := os.Getenv("PORT")
-
 	srv := &http.Server{
-		Addr:         ":" + port,
+		Addr:         ":" + cfg.HTTP.Port,
 		Handler:      app.Router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
 
 	go func() {
@@ -48,18 +61,46 @@ func main() {
 		}
 	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	serveGRPC(app.GRPCServer, cfg.GRPC.Port)
+	serveGRPC(app.APIServer, cfg.GRPC.APIPort)
+	if cfg.GRPC.APIPort != "" && cfg.GRPC.APITLSCertFile == "" {
+		slog.Warn("The auth.v1 gRPC API accepts passwords without TLS, keep its port private", "port", cfg.GRPC.APIPort)
+	}
+
+	<-ctx.Done()
+	stop()
 
 	slog.Info("Received shutdown signal, starting graceful shutdown")
 
-	ctxShut, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctxShut, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancel()
 
+	app.GRPCServer.GracefulStop()
+	app.APIServer.GracefulStop()
+
 	if err := srv.Shutdown(ctxShut); err != nil {
 		slog.Error("Server forced to shutdown", "error", err)
 	} else {
 		slog.Info("Server stopped gracefully")
 	}
 }
+
+func serveGRPC(server *grpc.Server, port string) {
+
+	if port == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		slog.Error("Failed to listen for gRPC", "port", port, "error", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			slog.Error("gRPC server failed", "port", port, "error", err)
+			os.Exit(1)
+		}
+	}()
+}
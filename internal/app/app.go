@@ -1,73 +1,162 @@
 package app
 
 import (
+	authv1 "authservice/api/auth/v1"
+	"authservice/internal/config"
 	"authservice/internal/database"
 	"authservice/internal/errors"
+	"authservice/internal/extauthz"
+	"authservice/internal/grpcapi"
 	"authservice/internal/handler"
 	"authservice/internal/repository"
 	"authservice/internal/router"
 	"authservice/internal/service"
+	"authservice/internal/utils"
 	"context"
-	"os"
-	"time"
 
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type App struct {
-	DBPool *pgxpool.Pool
-	Router *chi.Mux
-	Redis  *redis.Client
+	DBPool     *pgxpool.Pool
+	Router     *chi.Mux
+	GRPCServer *grpc.Server
+	APIServer  *grpc.Server
+	Redis      *redis.Client
 }
 
-func NewApp(ctx context.Context) (*App, error) {
+func NewApp(ctx context.Context, cfg *config.Config) (*App, error) {
 
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		return nil, errors.NewError(errors.ErrorTypeInternal, "DATABASE_URL environment variable is required", nil)
+	keyring, err := utils.LoadKeyring(cfg.JWT)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed to load JWT signing keys", err)
+	}
+	tokens := utils.NewTokenIssuer(cfg, keyring)
+	trustedProxies := utils.NewTrustedProxies(cfg.ForwardAuth.TrustedProxies)
+
+	encryptionKey, err := cfg.JWT.EncryptionKey()
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "invalid JWT key encryption key", err)
+	}
+
+	extAuthzOptions, err := grpcServerOptions(cfg.GRPC.TLSCertFile, cfg.GRPC.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	apiOptions, err := grpcServerOptions(cfg.GRPC.APITLSCertFile, cfg.GRPC.APITLSKeyFile)
+	if err != nil {
+		return nil, err
 	}
 
 	pool, err := database.NewPool(ctx, database.Config{
-		URL:            dsn,
-		MaxConns:       10,
-		MaxConnIdle:    5 * time.Minute,
-		ConnectTimeout: 3 * time.Second,
+		URL:            cfg.Database.URL,
+		MaxConns:       cfg.Database.MaxConns,
+		MaxConnIdle:    cfg.Database.MaxConnIdle,
+		ConnectTimeout: cfg.Database.ConnectTimeout,
 	})
 	if err != nil {
 		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to connect to database", err)
 	}
 
-	redisAddr := os.Getenv("REDIS_ADDR")
-	redisPort := os.Getenv("REDIS_PORT")
-	if redisAddr == "" || redisPort == "" {
-		return nil, errors.NewError(errors.ErrorTypeInternal, "REDIS_ADDR and REDIS_PORT environment variables are required", nil)
-	}
-
 	redisClient, err := database.NewRedisClient(database.RedisConfig{
-		Addr:     redisAddr + ":" + redisPort,
-		Password: os.Getenv("REDIS_PASSWORD"),
-		DB:       0,
+		Addr:         cfg.Redis.Addr + ":" + cfg.Redis.Port,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		DialTimeout:  cfg.Redis.DialTimeout,
+		ReadTimeout:  cfg.Redis.ReadTimeout,
+		WriteTimeout: cfg.Redis.WriteTimeout,
 	})
 	if err != nil {
 		pool.Close()
 		return nil, errors.NewError(errors.ErrorTypeRedis, "failed to connect to Redis", err)
 	}
 
-	blackList := service.NewBlacklistService(redisClient)
+	blackList := service.NewBlacklistService(redisClient, cfg.JWT.AccessTokenTTL)
 
 	tokenRepo := repository.NewRefTokenRepository(pool)
-	authService := service.NewAuthService(tokenRepo, blackList)
-	authHandler := handler.NewAuthHandler(authService)
+	roleRepo := repository.NewRoleRepository(pool)
+	authService := service.NewAuthService(tokenRepo, roleRepo, blackList, tokens, cfg.Webhook)
+	rbacService := service.NewRBACService(roleRepo)
+	rbacHandler := handler.NewRBACHandler(rbacService)
+	adminSessionService := service.NewAdminSessionService(tokenRepo, blackList)
+	adminSessionHandler := handler.NewAdminSessionHandler(adminSessionService)
+	userRepo := repository.NewUserRepository(pool)
+	userService := service.NewUserService(userRepo)
+	mfaRepo := repository.NewMFARepository(pool)
+	mfaService := service.NewMFAService(mfaRepo, userRepo, redisClient, tokens, cfg.TOTP.Issuer)
+	authHandler := handler.NewAuthHandler(authService, userService, mfaService, tokens, cfg.Cookie, trustedProxies)
+	mfaHandler := handler.NewMFAHandler(authService, mfaService)
 
-	router := router.NewRouter(authHandler, blackList)
+	web, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.WebAuthn.RPDisplayName,
+		RPOrigins:     cfg.WebAuthn.RPOrigins,
+	})
+	if err != nil {
+		pool.Close()
+		redisClient.Close()
+		return nil, errors.NewError(errors.ErrorTypeInternal, "invalid WebAuthn configuration", err)
+	}
+	webAuthnRepo := repository.NewWebAuthnRepository(pool)
+	challenges := service.NewRedisChallengeStore(redisClient)
+	webAuthnService := service.NewWebAuthnService(web, webAuthnRepo, userRepo, challenges)
+	webAuthnHandler := handler.NewWebAuthnHandler(authService, webAuthnService, tokens, cfg.Cookie, trustedProxies)
+	var oauthHandler *handler.OAuthHandler
+	if cfg.OIDC.Issuer != "" {
+		oauthClientRepo := repository.NewOAuthClientRepository(pool)
+		oauthService := service.NewOAuthService(tokens, authService, oauthClientRepo, challenges)
+		oauthHandler = handler.NewOAuthHandler(oauthService, authService, cfg.OIDC, cfg.Cookie, trustedProxies)
+	}
+	wellKnownHandler := handler.NewWellKnownHandler(tokens)
+
+	forwardAuthHandler := handler.NewForwardAuthHandler(authService, cfg.ForwardAuth, cfg.Cookie, trustedProxies)
+
+	signingKeyRepo := repository.NewSigningKeyRepository(pool)
+	keyService := service.NewKeyService(tokens, signingKeyRepo, encryptionKey)
+	if err := keyService.Sync(ctx); err != nil {
+		pool.Close()
+		redisClient.Close()
+		return nil, err
+	}
+	keyHandler := handler.NewKeyHandler(keyService)
+	go keyService.Run(ctx, cfg.JWT.KeyRotationInterval)
+
+	router := router.NewRouter(authHandler, mfaHandler, webAuthnHandler, oauthHandler, wellKnownHandler, keyHandler, rbacHandler, adminSessionHandler, forwardAuthHandler, authService, rbacService)
+
+	grpcServer := grpc.NewServer(extAuthzOptions...)
+	authv3.RegisterAuthorizationServer(grpcServer, extauthz.NewServer(authService))
+
+	apiServer := grpc.NewServer(apiOptions...)
+	authv1.RegisterAuthServiceServer(apiServer, grpcapi.NewAuthServer(authService, userService, mfaService, tokens, trustedProxies))
 
 	app := &App{
-		DBPool: pool,
-		Router: router,
-		Redis:  redisClient,
+		DBPool:     pool,
+		Router:     router,
+		GRPCServer: grpcServer,
+		APIServer:  apiServer,
+		Redis:      redisClient,
 	}
 
 	return app, nil
 }
+
+func grpcServerOptions(certFile, keyFile string) ([]grpc.ServerOption, error) {
+
+	if certFile == "" {
+		return nil, nil
+	}
+
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed to load gRPC TLS certificate", err)
+	}
+
+	return []grpc.ServerOption{grpc.Creds(creds)}, nil
+}
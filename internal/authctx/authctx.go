@@ -0,0 +1,125 @@
+package authctx
+
+import (
+	"authservice/internal/ctxkeys"
+	"authservice/internal/errors"
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type Claims struct {
+	UserID    uuid.UUID
+	SessionID string
+	ClientID  string
+	TokenID   string
+	Scopes    []string
+	Roles     []string
+	AMR       []string
+	AuthTime  time.Time
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// FromMapClaims rejects tokens without a user and session, such as
+// client_credentials tokens.
+func FromMapClaims(mapClaims jwt.MapClaims) (*Claims, error) {
+
+	userIDStr, ok := mapClaims["uid"].(string)
+	if !ok {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid user ID in token claims", nil)
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid user ID format", err)
+	}
+
+	sessionID, ok := mapClaims["sid"].(string)
+	if !ok || sessionID == "" {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid session ID in token claims", nil)
+	}
+
+	claims := &Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Roles:     stringSlice(mapClaims["roles"]),
+		AMR:       stringSlice(mapClaims["amr"]),
+	}
+	claims.ClientID, _ = mapClaims["client_id"].(string)
+	claims.TokenID, _ = mapClaims["jti"].(string)
+	if scope, ok := mapClaims["scope"].(string); ok {
+		claims.Scopes = strings.Fields(scope)
+	}
+	if authTime, ok := mapClaims["auth_time"].(float64); ok {
+		claims.AuthTime = time.Unix(int64(authTime), 0)
+	}
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+
+	return claims, nil
+}
+
+func stringSlice(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, ctxkeys.ClaimsKey, claims)
+}
+
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(ctxkeys.ClaimsKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+func UserID(ctx context.Context) (uuid.UUID, bool) {
+	claims, ok := FromContext(ctx)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return claims.UserID, true
+}
+
+func SessionID(ctx context.Context) (string, bool) {
+	claims, ok := FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.SessionID, true
+}
+
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"authservice/internal/errors"
+	"bytes"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	HTTP         HTTPConfig         `yaml:"http"`
+	GRPC         GRPCConfig         `yaml:"grpc"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Redis        RedisConfig        `yaml:"redis"`
+	OIDC         OIDCConfig         `yaml:"oidc"`
+	JWT          JWTConfig          `yaml:"jwt"`
+	RefreshToken RefreshTokenConfig `yaml:"refresh_token"`
+	Cookie       CookieConfig       `yaml:"cookie"`
+	TOTP         TOTPConfig         `yaml:"totp"`
+	WebAuthn     WebAuthnConfig     `yaml:"webauthn"`
+	Webhook      WebhookConfig      `yaml:"webhook"`
+	ForwardAuth  ForwardAuthConfig  `yaml:"forward_auth"`
+}
+
+type HTTPConfig struct {
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// The auth.v1 API accepts passwords and should not be reachable without TLS.
+type GRPCConfig struct {
+	Port           string `yaml:"port"`
+	APIPort        string `yaml:"api_port"`
+	TLSCertFile    string `yaml:"tls_cert_file"`
+	TLSKeyFile     string `yaml:"tls_key_file"`
+	APITLSCertFile string `yaml:"api_tls_cert_file"`
+	APITLSKeyFile  string `yaml:"api_tls_key_file"`
+}
+
+type DatabaseConfig struct {
+	URL            string        `yaml:"url"`
+	MaxConns       int32         `yaml:"max_conns"`
+	MaxConnIdle    time.Duration `yaml:"max_conn_idle"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+}
+
+type RedisConfig struct {
+	Addr         string        `yaml:"addr"`
+	Port         string        `yaml:"port"`
+	Password     string        `yaml:"password"`
+	DB           int           `yaml:"db"`
+	PoolSize     int           `yaml:"pool_size"`
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
+type OIDCConfig struct {
+	Issuer     string `yaml:"issuer"`
+	LoginURL   string `yaml:"login_url"`
+	ConsentURL string `yaml:"consent_url"`
+}
+
+type JWTConfig struct {
+	Audience             string        `yaml:"audience"`
+	Secret               string        `yaml:"secret"`
+	SigningKeyFile       string        `yaml:"signing_key_file"`
+	SigningKeyID         string        `yaml:"signing_key_id"`
+	VerificationKeyFiles []string      `yaml:"verification_key_files"`
+	KeyRotationInterval  time.Duration `yaml:"key_rotation_interval"`
+	KeyEncryptionKey     string        `yaml:"key_encryption_key"`
+	AccessTokenTTL       time.Duration `yaml:"access_token_ttl"`
+}
+
+func (c JWTConfig) EncryptionKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(c.KeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+type RefreshTokenConfig struct {
+	TTL    time.Duration `yaml:"ttl"`
+	Pepper string        `yaml:"pepper"`
+}
+
+// The access token cookie is set for browser redirects with Path / and
+// SameSite Lax.
+type CookieConfig struct {
+	Name            string `yaml:"name"`
+	AccessTokenName string `yaml:"access_token_name"`
+	Path            string `yaml:"path"`
+	Domain          string `yaml:"domain"`
+	Secure          bool   `yaml:"secure"`
+	SameSite        string `yaml:"same_site"`
+}
+
+func (c CookieConfig) SameSiteMode() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+type TOTPConfig struct {
+	Issuer string `yaml:"issuer"`
+}
+
+type WebAuthnConfig struct {
+	RPID          string   `yaml:"rp_id"`
+	RPDisplayName string   `yaml:"rp_display_name"`
+	RPOrigins     []string `yaml:"rp_origins"`
+}
+
+type WebhookConfig struct {
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type ForwardAuthConfig struct {
+	Default   ForwardAuthUpstream            `yaml:"default"`
+	Upstreams map[string]ForwardAuthUpstream `yaml:"upstreams"`
+	// TrustedProxies are the CIDRs of the reverse proxies. X-Forwarded-*
+	// headers of other clients are ignored, so they cannot pick the
+	// settings of another host or the IP address stored on their sessions.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type ForwardAuthUpstream struct {
+	Audience      string   `yaml:"audience"`
+	LoginURL      string   `yaml:"login_url"`
+	RedirectParam string   `yaml:"redirect_param"`
+	CookieName    string   `yaml:"cookie_name"`
+	Scopes        []string `yaml:"scopes"`
+	Roles         []string `yaml:"roles"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Port:            "8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Port: "9090",
+		},
+		Database: DatabaseConfig{
+			MaxConns:       10,
+			MaxConnIdle:    5 * time.Minute,
+			ConnectTimeout: 3 * time.Second,
+		},
+		Redis: RedisConfig{
+			Port:         "6379",
+			PoolSize:     10,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+		},
+		JWT: JWTConfig{
+			AccessTokenTTL: 30 * time.Minute,
+		},
+		RefreshToken: RefreshTokenConfig{
+			TTL: 7 * 24 * time.Hour,
+		},
+		Cookie: CookieConfig{
+			Name:            "refresh_token",
+			AccessTokenName: "access_token",
+			Path:            "/refresh",
+			Secure:          true,
+			SameSite:        "strict",
+		},
+		TOTP: TOTPConfig{
+			Issuer: "AuthService",
+		},
+		Webhook: WebhookConfig{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+func Load(path string) (*Config, error) {
+
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "failed to read config file", err)
+		}
+		// Unknown keys are rejected, so misspelled settings do not silently
+		// keep their defaults.
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !stderrors.Is(err, io.EOF) {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "invalid config file "+path, err)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "invalid environment variables", err)
+	}
+
+	cfg.OIDC.Issuer = strings.TrimSuffix(cfg.OIDC.Issuer, "/")
+	if cfg.JWT.Audience == "" {
+		cfg.JWT.Audience = cfg.OIDC.Issuer
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "invalid configuration", err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) Validate() error {
+
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.HTTP.Port), "http.port: invalid port %q", c.HTTP.Port)
+	check(c.HTTP.ReadTimeout > 0, "http.read_timeout: must be positive")
+	check(c.HTTP.WriteTimeout > 0, "http.write_timeout: must be positive")
+	check(c.HTTP.IdleTimeout > 0, "http.idle_timeout: must be positive")
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout: must be positive")
+	check(c.GRPC.Port == "" || validPort(c.GRPC.Port), "grpc.port: invalid port %q", c.GRPC.Port)
+	check(c.GRPC.APIPort == "" || validPort(c.GRPC.APIPort), "grpc.api_port: invalid port %q", c.GRPC.APIPort)
+	check(c.GRPC.APIPort == "" || (c.GRPC.APIPort != c.GRPC.Port && c.GRPC.APIPort != c.HTTP.Port), "grpc.api_port: must differ from grpc.port and http.port")
+	check((c.GRPC.TLSCertFile == "") == (c.GRPC.TLSKeyFile == ""), "grpc: tls_cert_file and tls_key_file must be set together")
+	check((c.GRPC.APITLSCertFile == "") == (c.GRPC.APITLSKeyFile == ""), "grpc: api_tls_cert_file and api_tls_key_file must be set together")
+
+	check(c.Database.URL != "", "database.url: required")
+	check(c.Database.MaxConns > 0, "database.max_conns: must be positive")
+	check(c.Database.MaxConnIdle > 0, "database.max_conn_idle: must be positive")
+	check(c.Database.ConnectTimeout > 0, "database.connect_timeout: must be positive")
+
+	check(c.Redis.Addr != "", "redis.addr: required")
+	check(validPort(c.Redis.Port), "redis.port: invalid port %q", c.Redis.Port)
+	check(c.Redis.DB >= 0, "redis.db: must not be negative")
+	check(c.Redis.PoolSize > 0, "redis.pool_size: must be positive")
+	check(c.Redis.DialTimeout > 0, "redis.dial_timeout: must be positive")
+	check(c.Redis.ReadTimeout > 0, "redis.read_timeout: must be positive")
+	check(c.Redis.WriteTimeout > 0, "redis.write_timeout: must be positive")
+
+	check(c.OIDC.Issuer == "" || validURL(c.OIDC.Issuer), "oidc.issuer: must be an absolute http(s) URL")
+
+	check(c.OIDC.LoginURL == "" || validURL(c.OIDC.LoginURL), "oidc.login_url: must be an absolute http(s) URL")
+	check(c.OIDC.ConsentURL == "" || validURL(c.OIDC.ConsentURL), "oidc.consent_url: must be an absolute http(s) URL")
+
+	check(c.JWT.Audience != "", "jwt.audience: required when oidc.issuer is empty")
+	check(c.JWT.SigningKeyFile != "" || c.JWT.Secret != "", "jwt: signing_key_file or secret is required")
+	// Relying parties verify ID tokens with the published JWKS, which has
+	// no keys for the HS512 secret.
+	check(c.OIDC.Issuer == "" || c.JWT.SigningKeyFile != "", "jwt.signing_key_file: required when oidc.issuer is set")
+	check(c.JWT.KeyRotationInterval >= 0, "jwt.key_rotation_interval: must not be negative")
+	if _, err := c.JWT.EncryptionKey(); err != nil {
+		check(false, "jwt.key_encryption_key: invalid base64 AES-256 key: %v", err)
+	}
+	check(c.JWT.AccessTokenTTL > 0, "jwt.access_token_ttl: must be positive")
+
+	check(c.RefreshToken.TTL > c.JWT.AccessTokenTTL, "refresh_token.ttl: must be longer than jwt.access_token_ttl")
+	check(c.RefreshToken.Pepper != "", "refresh_token.pepper: required")
+
+	check(c.Cookie.Name != "", "cookie.name: required")
+	check(c.Cookie.AccessTokenName != "" && c.Cookie.AccessTokenName != c.Cookie.Name, "cookie.access_token_name: required and must differ from cookie.name")
+	check(strings.HasPrefix(c.Cookie.Path, "/"), "cookie.path: must start with /")
+	switch strings.ToLower(c.Cookie.SameSite) {
+	case "strict", "lax":
+	case "none":
+		check(c.Cookie.Secure, "cookie.same_site: none requires cookie.secure")
+	default:
+		check(false, "cookie.same_site: must be strict, lax or none, got %q", c.Cookie.SameSite)
+	}
+
+	check(c.TOTP.Issuer != "", "totp.issuer: required")
+
+	check(c.WebAuthn.RPID != "", "webauthn.rp_id: required")
+	check(c.WebAuthn.RPDisplayName != "", "webauthn.rp_display_name: required")
+	check(len(c.WebAuthn.RPOrigins) > 0, "webauthn.rp_origins: required")
+	for _, origin := range c.WebAuthn.RPOrigins {
+		check(validURL(origin), "webauthn.rp_origins: invalid origin %q", origin)
+	}
+
+	check(c.Webhook.URL == "" || validURL(c.Webhook.URL), "webhook.url: must be an absolute http(s) URL")
+	check(c.Webhook.Timeout > 0, "webhook.timeout: must be positive")
+
+	check(c.ForwardAuth.Default.LoginURL == "" || validURL(c.ForwardAuth.Default.LoginURL), "forward_auth.default.login_url: must be an absolute http(s) URL")
+	for host, upstream := range c.ForwardAuth.Upstreams {
+		check(host != "" && host == strings.ToLower(host) && !strings.Contains(host, ":"), "forward_auth.upstreams: host %q must be lower case without port", host)
+		check(upstream.LoginURL == "" || validURL(upstream.LoginURL), "forward_auth.upstreams.%s.login_url: must be an absolute http(s) URL", host)
+	}
+	for _, proxy := range c.ForwardAuth.TrustedProxies {
+		_, err := netip.ParsePrefix(proxy)
+		check(err == nil, "forward_auth.trusted_proxies: invalid CIDR %q", proxy)
+	}
+
+	return stderrors.Join(problems...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
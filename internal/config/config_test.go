@@ -0,0 +1,221 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKeyEncryptionKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.Database.URL = "postgres://localhost/auth"
+	cfg.Redis.Addr = "localhost"
+	cfg.JWT.Audience = "https://auth.example.com"
+	cfg.JWT.Secret = "secret"
+	cfg.JWT.KeyEncryptionKey = testKeyEncryptionKey
+	cfg.RefreshToken.Pepper = "pepper"
+	cfg.WebAuthn.RPID = "example.com"
+	cfg.WebAuthn.RPDisplayName = "Example"
+	cfg.WebAuthn.RPOrigins = []string{"https://example.com"}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "every problem at once",
+			modify: func(cfg *Config) {
+				cfg.HTTP.Port = "http"
+				cfg.Database.URL = ""
+				cfg.RefreshToken.Pepper = ""
+				cfg.Cookie.SameSite = "loose"
+				cfg.ForwardAuth.TrustedProxies = []string{"10.0.0.0/33"}
+			},
+			want: []string{
+				`http.port: invalid port "http"`,
+				"database.url: required",
+				"refresh_token.pepper: required",
+				`cookie.same_site: must be strict, lax or none, got "loose"`,
+				`forward_auth.trusted_proxies: invalid CIDR "10.0.0.0/33"`,
+			},
+		},
+		{
+			name: "refresh token shorter than access token",
+			modify: func(cfg *Config) {
+				cfg.RefreshToken.TTL = cfg.JWT.AccessTokenTTL
+			},
+			want: []string{"refresh_token.ttl: must be longer than jwt.access_token_ttl"},
+		},
+		{
+			name: "issuer without signing key file",
+			modify: func(cfg *Config) {
+				cfg.OIDC.Issuer = "https://auth.example.com"
+			},
+			want: []string{"jwt.signing_key_file: required when oidc.issuer is set"},
+		},
+		{
+			name: "same gRPC ports",
+			modify: func(cfg *Config) {
+				cfg.GRPC.APIPort = cfg.GRPC.Port
+			},
+			want: []string{"grpc.api_port: must differ from grpc.port and http.port"},
+		},
+		{
+			name: "TLS certificate without key",
+			modify: func(cfg *Config) {
+				cfg.GRPC.APITLSCertFile = "/tls/api.crt"
+			},
+			want: []string{"grpc: api_tls_cert_file and api_tls_key_file must be set together"},
+		},
+		{
+			name: "SameSite none without Secure",
+			modify: func(cfg *Config) {
+				cfg.Cookie.SameSite = "none"
+				cfg.Cookie.Secure = false
+			},
+			want: []string{"cookie.same_site: none requires cookie.secure"},
+		},
+		{
+			name: "upstream host with port",
+			modify: func(cfg *Config) {
+				cfg.ForwardAuth.Upstreams = map[string]ForwardAuthUpstream{"admin.example.com:443": {}}
+			},
+			want: []string{`forward_auth.upstreams: host "admin.example.com:443" must be lower case without port`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate succeeded, want errors")
+			}
+			problems := strings.Split(err.Error(), "\n")
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate reported %d problems, want %d:\n%v", len(problems), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if problems[i] != want {
+					t.Errorf("problem %d = %q, want %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	const file = `
+database:
+  url: postgres://localhost/auth
+redis:
+  addr: localhost
+oidc:
+  issuer: https://auth.example.com/
+jwt:
+  secret: secret
+  signing_key_file: /keys/signing.pem
+  key_encryption_key: AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=
+  access_token_ttl: 10m
+refresh_token:
+  pepper: pepper
+webauthn:
+  rp_id: example.com
+  rp_display_name: Example
+  rp_origins: [https://example.com]
+`
+
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "file",
+			file: file,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.OIDC.Issuer != "https://auth.example.com" || cfg.JWT.Audience != cfg.OIDC.Issuer {
+					t.Errorf("issuer = %q, audience = %q, want both https://auth.example.com", cfg.OIDC.Issuer, cfg.JWT.Audience)
+				}
+				if cfg.JWT.AccessTokenTTL != 10*time.Minute || cfg.HTTP.Port != "8080" {
+					t.Errorf("access token TTL = %s, port = %q, want 10m from the file and the default port", cfg.JWT.AccessTokenTTL, cfg.HTTP.Port)
+				}
+			},
+		},
+		{
+			name: "environment overrides file",
+			file: file,
+			env:  map[string]string{"PORT": "9000", "ACCESS_TOKEN_TTL": "5m", "WEBAUTHN_RP_ORIGINS": "https://a.example.com, https://b.example.com"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.HTTP.Port != "9000" || cfg.JWT.AccessTokenTTL != 5*time.Minute {
+					t.Errorf("port = %q, access token TTL = %s, want the environment values", cfg.HTTP.Port, cfg.JWT.AccessTokenTTL)
+				}
+				if len(cfg.WebAuthn.RPOrigins) != 2 || cfg.WebAuthn.RPOrigins[1] != "https://b.example.com" {
+					t.Errorf("rp_origins = %q, want both origins", cfg.WebAuthn.RPOrigins)
+				}
+			},
+		},
+		{
+			name:    "unknown key",
+			file:    file + "\nhttp:\n  prot: \"9000\"\n",
+			wantErr: "field prot not found",
+		},
+		{
+			name:    "invalid environment variable",
+			file:    file,
+			env:     map[string]string{"ACCESS_TOKEN_TTL": "soon"},
+			wantErr: `ACCESS_TOKEN_TTL: invalid value "soon"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatalf("write config file: %v", err)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
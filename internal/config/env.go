@@ -0,0 +1,174 @@
+package config
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv treats empty variables as unset, so .env files can list every name.
+func applyEnv(cfg *Config) error {
+
+	env := envReader{}
+
+	env.String(&cfg.HTTP.Port, "PORT")
+	env.Duration(&cfg.HTTP.ReadTimeout, "HTTP_READ_TIMEOUT")
+	env.Duration(&cfg.HTTP.WriteTimeout, "HTTP_WRITE_TIMEOUT")
+	env.Duration(&cfg.HTTP.IdleTimeout, "HTTP_IDLE_TIMEOUT")
+	env.Duration(&cfg.HTTP.ShutdownTimeout, "HTTP_SHUTDOWN_TIMEOUT")
+	env.String(&cfg.GRPC.Port, "GRPC_PORT")
+	env.String(&cfg.GRPC.APIPort, "GRPC_API_PORT")
+	env.String(&cfg.GRPC.TLSCertFile, "GRPC_TLS_CERT_FILE")
+	env.String(&cfg.GRPC.TLSKeyFile, "GRPC_TLS_KEY_FILE")
+	env.String(&cfg.GRPC.APITLSCertFile, "GRPC_API_TLS_CERT_FILE")
+	env.String(&cfg.GRPC.APITLSKeyFile, "GRPC_API_TLS_KEY_FILE")
+
+	env.String(&cfg.Database.URL, "DATABASE_URL")
+	env.Int32(&cfg.Database.MaxConns, "DATABASE_MAX_CONNS")
+	env.Duration(&cfg.Database.MaxConnIdle, "DATABASE_MAX_CONN_IDLE")
+	env.Duration(&cfg.Database.ConnectTimeout, "DATABASE_CONNECT_TIMEOUT")
+
+	env.String(&cfg.Redis.Addr, "REDIS_ADDR")
+	env.String(&cfg.Redis.Port, "REDIS_PORT")
+	env.String(&cfg.Redis.Password, "REDIS_PASSWORD")
+	env.Int(&cfg.Redis.DB, "REDIS_DB")
+	env.Int(&cfg.Redis.PoolSize, "REDIS_POOL_SIZE")
+	env.Duration(&cfg.Redis.DialTimeout, "REDIS_DIAL_TIMEOUT")
+	env.Duration(&cfg.Redis.ReadTimeout, "REDIS_READ_TIMEOUT")
+	env.Duration(&cfg.Redis.WriteTimeout, "REDIS_WRITE_TIMEOUT")
+
+	env.String(&cfg.OIDC.Issuer, "OIDC_ISSUER")
+	env.String(&cfg.OIDC.LoginURL, "OIDC_LOGIN_URL")
+	env.String(&cfg.OIDC.ConsentURL, "OIDC_CONSENT_URL")
+
+	env.String(&cfg.JWT.Audience, "JWT_AUDIENCE")
+	env.String(&cfg.JWT.Secret, "ACCESS_SECRET")
+	env.String(&cfg.JWT.SigningKeyFile, "JWT_SIGNING_KEY_FILE")
+	env.String(&cfg.JWT.SigningKeyID, "JWT_SIGNING_KEY_ID")
+	env.List(&cfg.JWT.VerificationKeyFiles, "JWT_VERIFICATION_KEY_FILES")
+	env.Duration(&cfg.JWT.KeyRotationInterval, "JWT_KEY_ROTATION_INTERVAL")
+	env.String(&cfg.JWT.KeyEncryptionKey, "JWT_KEY_ENCRYPTION_KEY")
+	env.Duration(&cfg.JWT.AccessTokenTTL, "ACCESS_TOKEN_TTL")
+
+	env.Duration(&cfg.RefreshToken.TTL, "REFRESH_TOKEN_TTL")
+	env.String(&cfg.RefreshToken.Pepper, "REFRESH_TOKEN_PEPPER")
+
+	env.String(&cfg.Cookie.Name, "REFRESH_COOKIE_NAME")
+	env.String(&cfg.Cookie.AccessTokenName, "ACCESS_COOKIE_NAME")
+	env.String(&cfg.Cookie.Path, "REFRESH_COOKIE_PATH")
+	env.String(&cfg.Cookie.Domain, "REFRESH_COOKIE_DOMAIN")
+	env.Bool(&cfg.Cookie.Secure, "REFRESH_COOKIE_SECURE")
+	env.String(&cfg.Cookie.SameSite, "REFRESH_COOKIE_SAME_SITE")
+
+	env.String(&cfg.TOTP.Issuer, "TOTP_ISSUER")
+
+	env.String(&cfg.WebAuthn.RPID, "WEBAUTHN_RP_ID")
+	env.String(&cfg.WebAuthn.RPDisplayName, "WEBAUTHN_RP_DISPLAY_NAME")
+	env.List(&cfg.WebAuthn.RPOrigins, "WEBAUTHN_RP_ORIGINS")
+
+	env.String(&cfg.Webhook.URL, "WEBHOOK_URL")
+	env.Duration(&cfg.Webhook.Timeout, "WEBHOOK_TIMEOUT")
+
+	env.String(&cfg.ForwardAuth.Default.LoginURL, "FORWARD_AUTH_LOGIN_URL")
+	env.List(&cfg.ForwardAuth.TrustedProxies, "FORWARD_AUTH_TRUSTED_PROXIES")
+
+	return stderrors.Join(env.problems...)
+}
+
+type envReader struct {
+	problems []error
+}
+
+func (e *envReader) lookup(name string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(name))
+	return value, value != ""
+}
+
+func (e *envReader) fail(name, value string, err error) {
+	e.problems = append(e.problems, fmt.Errorf("%s: invalid value %q: %w", name, value, err))
+}
+
+func (e *envReader) String(dst *string, name string) {
+	if value, ok := e.lookup(name); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) List(dst *[]string, name string) {
+
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e *envReader) Duration(dst *time.Duration, name string) {
+
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(name, value, err)
+		return
+	}
+	*dst = d
+}
+
+func (e *envReader) Int(dst *int, name string) {
+
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(name, value, err)
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) Int32(dst *int32, name string) {
+
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		e.fail(name, value, err)
+		return
+	}
+	*dst = int32(n)
+}
+
+func (e *envReader) Bool(dst *bool, name string) {
+
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(name, value, err)
+		return
+	}
+	*dst = b
+}
@@ -3,6 +3,9 @@ package errors
 import (
 	"errors"
 	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ErrorType string
@@ -10,7 +13,9 @@ type ErrorType string
 const (
 	ErrorTypeValidation ErrorType = "validation_error"
 	ErrorTypeAuth       ErrorType = "authentication_error"
+	ErrorTypeForbidden  ErrorType = "forbidden"
 	ErrorTypeNotFound   ErrorType = "not_found"
+	ErrorTypeConflict   ErrorType = "conflict"
 	ErrorTypeInternal   ErrorType = "internal_error"
 	ErrorTypeDatabase   ErrorType = "database_error"
 	ErrorTypeRedis      ErrorType = "redis_error"
@@ -30,6 +35,10 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
 func NewError(errorType ErrorType, message string, err error) *AppError {
 	return &AppError{
 		Type:    errorType,
@@ -54,11 +63,39 @@ func (e *AppError) GetHTTPStatus() int {
 		return 400
 	case ErrorTypeAuth:
 		return 401
+	case ErrorTypeForbidden:
+		return 403
 	case ErrorTypeNotFound:
 		return 404
+	case ErrorTypeConflict:
+		return 409
 	case ErrorTypeDatabase, ErrorTypeRedis, ErrorTypeInternal:
 		return 500
 	default:
 		return 500
 	}
 }
+
+func (e *AppError) GetGRPCCode() codes.Code {
+	switch e.Type {
+	case ErrorTypeValidation:
+		return codes.InvalidArgument
+	case ErrorTypeAuth:
+		return codes.Unauthenticated
+	case ErrorTypeForbidden:
+		return codes.PermissionDenied
+	case ErrorTypeNotFound:
+		return codes.NotFound
+	case ErrorTypeConflict:
+		return codes.AlreadyExists
+	case ErrorTypeDatabase, ErrorTypeRedis, ErrorTypeInternal:
+		return codes.Internal
+	default:
+		return codes.Internal
+	}
+}
+
+// GRPCStatus exposes only the message, not the cause, like the HTTP responses.
+func (e *AppError) GRPCStatus() *status.Status {
+	return status.New(e.GetGRPCCode(), e.Message)
+}
@@ -0,0 +1,128 @@
+package extauthz
+
+import (
+	"authservice/internal/authctx"
+	"authservice/internal/errors"
+	"authservice/internal/handler"
+	"authservice/internal/service"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Headers added to the upstream request for an authenticated user. They
+// overwrite values sent by the client, so upstreams can trust them.
+const (
+	HeaderUserID    = "x-user-id"
+	HeaderSessionID = "x-session-id"
+	HeaderScopes    = "x-scopes"
+)
+
+// ContextExtensionAudience is set in the Envoy route config, so clients cannot
+// change it.
+const ContextExtensionAudience = "audience"
+
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+
+	AuthService *service.AuthService
+}
+
+func NewServer(authService *service.AuthService) *Server {
+	return &Server{
+		AuthService: authService,
+	}
+}
+
+// Check returns failures of the check itself as gRPC errors, so Envoy applies
+// its failure_mode_allow setting.
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+
+	headers := req.GetAttributes().GetRequest().GetHttp().GetHeaders()
+	accessToken := bearerToken(headers["authorization"])
+
+	var claims *authctx.Claims
+	var err error
+	if audience := req.GetAttributes().GetContextExtensions()[ContextExtensionAudience]; audience != "" {
+		claims, err = s.AuthService.VerifyAccessTokenFor(accessToken, audience)
+	} else {
+		claims, err = s.AuthService.VerifyAccessToken(accessToken)
+	}
+	if err != nil {
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.Type != errors.ErrorTypeAuth {
+			slog.Error("Failed to check request", "error", err)
+			return nil, status.Error(codes.Unavailable, "authorization check failed")
+		}
+
+		slog.Info("Request denied by ext_authz", "host", req.GetAttributes().GetRequest().GetHttp().GetHost(), "error", err)
+		return deniedResponse(appErr), nil
+	}
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code.Code_OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{
+				Headers: []*corev3.HeaderValueOption{
+					overwriteHeader(HeaderUserID, claims.UserID.String()),
+					overwriteHeader(HeaderSessionID, claims.SessionID),
+					overwriteHeader(HeaderScopes, strings.Join(claims.Scopes, " ")),
+				},
+			},
+		},
+	}, nil
+}
+
+func bearerToken(header string) string {
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+func overwriteHeader(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
+
+func deniedResponse(appErr *errors.AppError) *authv3.CheckResponse {
+
+	body, err := json.Marshal(handler.Response{
+		Success: false,
+		Error: &handler.ErrorResponse{
+			Type:    string(appErr.Type),
+			Message: appErr.Message,
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to encode denied response", "error", err)
+	}
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code.Code_UNAUTHENTICATED), Message: appErr.Message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status: &typev3.HttpStatus{Code: typev3.StatusCode_Unauthorized},
+				Headers: []*corev3.HeaderValueOption{
+					overwriteHeader("content-type", "application/json"),
+					overwriteHeader("www-authenticate", `Bearer error="invalid_token"`),
+				},
+				Body: string(body),
+			},
+		},
+	}
+}
@@ -0,0 +1,211 @@
+package extauthz
+
+import (
+	"authservice/internal/config"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testIssuer = "https://auth.example.com"
+
+// newTestClient serves the ext_authz API over an in-memory connection. The
+// token blacklist lives in miniredis.
+func newTestClient(t *testing.T) (authv3.AuthorizationClient, *service.AuthService) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.OIDC.Issuer = testIssuer
+	cfg.JWT.Audience = testIssuer
+	tokens := utils.NewTokenIssuer(cfg, utils.NewKeyring(utils.NewHMACSigningKey("test", []byte("test-secret"))))
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	blacklist := service.NewBlacklistService(redisClient, cfg.JWT.AccessTokenTTL)
+	authService := service.NewAuthService(nil, nil, blacklist, tokens, cfg.Webhook)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(server, NewServer(authService))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return authv3.NewAuthorizationClient(conn), authService
+}
+
+func newAccessToken(t *testing.T, tokens *utils.TokenIssuer, userID, sessionID string, audience ...string) string {
+	t.Helper()
+
+	token, err := tokens.GenerateJWT(utils.AccessTokenClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Scope:     "openid email",
+		Audience:  audience,
+	})
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	return token
+}
+
+func checkRequest(headers, contextExtensions map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			ContextExtensions: contextExtensions,
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:  "GET",
+					Host:    "api.example.com",
+					Path:    "/orders",
+					Headers: headers,
+				},
+			},
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	client, authService := newTestClient(t)
+
+	userID := uuid.NewString()
+	sessionID := uuid.NewString()
+	validToken := newAccessToken(t, authService.Tokens, userID, sessionID)
+
+	ordersAudience := "https://orders.example.com"
+	ordersToken := newAccessToken(t, authService.Tokens, userID, sessionID, ordersAudience)
+	ordersRoute := map[string]string{ContextExtensionAudience: ordersAudience}
+
+	revokedSessionID := uuid.NewString()
+	revokedToken := newAccessToken(t, authService.Tokens, uuid.NewString(), revokedSessionID)
+	if err := authService.Blacklist.AddToken(revokedSessionID, time.Minute); err != nil {
+		t.Fatalf("blacklist token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		extensions map[string]string
+		allowed    bool
+	}{
+		{
+			name:    "valid token",
+			headers: map[string]string{"authorization": "Bearer " + validToken},
+			allowed: true,
+		},
+		{
+			name: "spoofed identity headers",
+			headers: map[string]string{
+				"authorization": "Bearer " + validToken,
+				HeaderUserID:    uuid.NewString(),
+				HeaderSessionID: "spoofed",
+				HeaderScopes:    "admin",
+			},
+			allowed: true,
+		},
+		{
+			name:       "token for the route audience",
+			headers:    map[string]string{"authorization": "Bearer " + ordersToken},
+			extensions: ordersRoute,
+			allowed:    true,
+		},
+		{
+			name:       "own audience token on an audience route",
+			headers:    map[string]string{"authorization": "Bearer " + validToken},
+			extensions: ordersRoute,
+		},
+		{
+			name:    "route audience token without extension",
+			headers: map[string]string{"authorization": "Bearer " + ordersToken},
+		},
+		{
+			name:    "missing token",
+			headers: map[string]string{},
+		},
+		{
+			name:    "invalid token",
+			headers: map[string]string{"authorization": "Bearer not-a-jwt"},
+		},
+		{
+			name:    "blacklisted token",
+			headers: map[string]string{"authorization": "Bearer " + revokedToken},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			response, err := client.Check(context.Background(), checkRequest(tt.headers, tt.extensions))
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+
+			if !tt.allowed {
+				if response.GetStatus().GetCode() != int32(code.Code_UNAUTHENTICATED) {
+					t.Errorf("status = %v, want UNAUTHENTICATED", code.Code(response.GetStatus().GetCode()))
+				}
+				denied := response.GetDeniedResponse()
+				if denied == nil {
+					t.Fatal("missing denied response")
+				}
+				if denied.GetStatus().GetCode() != typev3.StatusCode_Unauthorized {
+					t.Errorf("HTTP status = %v, want 401", denied.GetStatus().GetCode())
+				}
+				return
+			}
+
+			if response.GetStatus().GetCode() != int32(code.Code_OK) {
+				t.Fatalf("status = %v, want OK", code.Code(response.GetStatus().GetCode()))
+			}
+			ok := response.GetOkResponse()
+			if ok == nil {
+				t.Fatal("missing ok response")
+			}
+
+			want := map[string]string{
+				HeaderUserID:    userID,
+				HeaderSessionID: sessionID,
+				HeaderScopes:    "openid email",
+			}
+			for _, header := range ok.GetHeaders() {
+				key := header.GetHeader().GetKey()
+				if header.GetAppendAction() != corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD {
+					t.Errorf("%s append action = %v, want OVERWRITE_IF_EXISTS_OR_ADD", key, header.GetAppendAction())
+				}
+				if value, ok := want[key]; !ok || header.GetHeader().GetValue() != value {
+					t.Errorf("%s = %q, want %q", key, header.GetHeader().GetValue(), value)
+				}
+				delete(want, key)
+			}
+			for key := range want {
+				t.Errorf("missing header %s", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package grpcapi
+
+import (
+	authv1 "authservice/api/auth/v1"
+	"authservice/internal/ctxkeys"
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type AuthServer struct {
+	authv1.UnimplementedAuthServiceServer
+
+	AuthService    *service.AuthService
+	UserService    *service.UserService
+	MFAService     *service.MFAService
+	Tokens         *utils.TokenIssuer
+	TrustedProxies utils.TrustedProxies
+}
+
+func NewAuthServer(authService *service.AuthService, userService *service.UserService, mfaService *service.MFAService, tokens *utils.TokenIssuer, trustedProxies utils.TrustedProxies) *AuthServer {
+	return &AuthServer{
+		AuthService:    authService,
+		UserService:    userService,
+		MFAService:     mfaService,
+		Tokens:         tokens,
+		TrustedProxies: trustedProxies,
+	}
+}
+
+func statusError(err error) error {
+
+	if appErr, ok := errors.IsAppError(err); ok {
+		return appErr.GRPCStatus().Err()
+	}
+
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+func metadataValue(ctx context.Context, key string) string {
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func bearerToken(ctx context.Context) string {
+
+	scheme, token, ok := strings.Cut(metadataValue(ctx, "authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+func (s *AuthServer) sessionContext(ctx context.Context) context.Context {
+
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	ipAddress := s.TrustedProxies.ClientIP(remoteAddr, strings.Join(md.Get("x-forwarded-for"), ","))
+
+	ctx = context.WithValue(ctx, ctxkeys.UserAgentKey, metadataValue(ctx, "user-agent"))
+	return context.WithValue(ctx, ctxkeys.IPAddressKey, ipAddress)
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func (s *AuthServer) CreateSession(ctx context.Context, req *authv1.CreateSessionRequest) (*authv1.CreateSessionResponse, error) {
+
+	if req.GetMfaToken() != "" {
+		return s.createSessionMFA(ctx, req)
+	}
+
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		return nil, statusError(errors.NewError(errors.ErrorTypeValidation, "Email and password are required", nil))
+	}
+
+	user, err := s.UserService.Authenticate(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		slog.Error("Failed to authenticate user", "error", err)
+		return nil, statusError(err)
+	}
+
+	mfaEnabled, err := s.MFAService.IsTOTPEnabled(ctx, user.ID)
+	if err != nil {
+		slog.Error("Failed to check second factor", "error", err)
+		return nil, statusError(err)
+	}
+
+	if mfaEnabled {
+		mfaToken, err := s.Tokens.GenerateMFAToken(user.ID.String())
+		if err != nil {
+			slog.Error("Failed to create MFA token", "error", err)
+			return nil, statusError(err)
+		}
+
+		return &authv1.CreateSessionResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	accessToken, refreshToken, err := s.AuthService.NewSession(s.sessionContext(ctx), user.ID, []string{service.AMRPassword})
+	if err != nil {
+		slog.Error("Failed to create new session", "error", err)
+		return nil, statusError(err)
+	}
+
+	return &authv1.CreateSessionResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *AuthServer) createSessionMFA(ctx context.Context, req *authv1.CreateSessionRequest) (*authv1.CreateSessionResponse, error) {
+
+	if req.GetCode() == "" && req.GetRecoveryCode() == "" {
+		return nil, statusError(errors.NewError(errors.ErrorTypeValidation, "MFA token and code are required", nil))
+	}
+
+	userID, amr, err := s.MFAService.CompleteLogin(ctx, req.GetMfaToken(), req.GetCode(), req.GetRecoveryCode())
+	if err != nil {
+		slog.Error("Failed to verify second factor", "error", err)
+		return nil, statusError(err)
+	}
+
+	accessToken, refreshToken, err := s.AuthService.NewSession(s.sessionContext(ctx), userID, amr)
+	if err != nil {
+		slog.Error("Failed to create new session", "error", err)
+		return nil, statusError(err)
+	}
+
+	return &authv1.CreateSessionResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *AuthServer) Refresh(ctx context.Context, req *authv1.RefreshRequest) (*authv1.RefreshResponse, error) {
+
+	if req.GetRefreshToken() == "" {
+		return nil, statusError(errors.NewError(errors.ErrorTypeAuth, "Refresh token required", nil))
+	}
+
+	accessToken, refreshToken, err := s.AuthService.RefreshSession(s.sessionContext(ctx), bearerToken(ctx), req.GetRefreshToken())
+	if err != nil {
+		slog.Error("Failed to refresh session", "error", err)
+		return nil, statusError(err)
+	}
+
+	return &authv1.RefreshResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *AuthServer) Revoke(ctx context.Context, req *authv1.RevokeRequest) (*authv1.RevokeResponse, error) {
+
+	accessToken := bearerToken(ctx)
+	if accessToken == "" {
+		return nil, statusError(errors.NewError(errors.ErrorTypeAuth, "Access token required", nil))
+	}
+	if req.GetRefreshToken() == "" {
+		return nil, statusError(errors.NewError(errors.ErrorTypeAuth, "Refresh token required", nil))
+	}
+
+	if err := s.AuthService.RevokeSession(ctx, accessToken, req.GetRefreshToken()); err != nil {
+		slog.Error("Failed to revoke session", "error", err)
+		return nil, statusError(err)
+	}
+
+	return &authv1.RevokeResponse{}, nil
+}
+
+func (s *AuthServer) ValidateToken(ctx context.Context, req *authv1.ValidateTokenRequest) (*authv1.ValidateTokenResponse, error) {
+
+	claims, err := s.AuthService.VerifyAccessToken(req.GetAccessToken())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &authv1.ValidateTokenResponse{
+		UserId:    claims.UserID.String(),
+		SessionId: claims.SessionID,
+		ClientId:  claims.ClientID,
+		TokenId:   claims.TokenID,
+		Scopes:    claims.Scopes,
+		Roles:     claims.Roles,
+		Amr:       claims.AMR,
+		AuthTime:  timestamp(claims.AuthTime),
+		IssuedAt:  timestamp(claims.IssuedAt),
+		ExpiresAt: timestamp(claims.ExpiresAt),
+	}, nil
+}
+
+func (s *AuthServer) ListSessions(ctx context.Context, req *authv1.ListSessionsRequest) (*authv1.ListSessionsResponse, error) {
+
+	claims, err := s.AuthService.VerifyFirstPartyToken(bearerToken(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	sessions, err := s.AuthService.ListSessions(ctx, claims.UserID, claims.SessionID)
+	if err != nil {
+		slog.Error("Failed to list sessions", "error", err)
+		return nil, statusError(err)
+	}
+
+	response := &authv1.ListSessionsResponse{
+		Sessions: make([]*authv1.Session, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, &authv1.Session{
+			SessionId:       session.SessionID,
+			UserAgent:       session.UserAgent,
+			IpAddress:       session.IPAddress,
+			CreatedAt:       timestamp(session.CreatedAt),
+			LastRefreshedAt: timestamp(session.LastRefreshedAt),
+			Current:         session.Current,
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"authservice/internal/ctxkeys"
+	"authservice/internal/errors"
+	"authservice/internal/utils"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{name: "validation", err: errors.NewError(errors.ErrorTypeValidation, "Invalid email", nil), wantCode: codes.InvalidArgument, wantMessage: "Invalid email"},
+		{name: "authentication", err: errors.NewError(errors.ErrorTypeAuth, "Invalid token", nil), wantCode: codes.Unauthenticated, wantMessage: "Invalid token"},
+		{name: "forbidden", err: errors.NewError(errors.ErrorTypeForbidden, "Forbidden", nil), wantCode: codes.PermissionDenied, wantMessage: "Forbidden"},
+		{name: "not found", err: errors.NewError(errors.ErrorTypeNotFound, "Session not found", nil), wantCode: codes.NotFound, wantMessage: "Session not found"},
+		{name: "conflict", err: errors.NewError(errors.ErrorTypeConflict, "User already exists", nil), wantCode: codes.AlreadyExists, wantMessage: "User already exists"},
+		{
+			name:        "database cause is hidden",
+			err:         errors.NewError(errors.ErrorTypeDatabase, "Failed to get user", stderrors.New("connection refused")),
+			wantCode:    codes.Internal,
+			wantMessage: "Failed to get user",
+		},
+		{name: "redis", err: errors.NewError(errors.ErrorTypeRedis, "Failed to check token", nil), wantCode: codes.Internal, wantMessage: "Failed to check token"},
+		{
+			name:        "wrapped app error",
+			err:         fmt.Errorf("refresh: %w", errors.NewError(errors.ErrorTypeAuth, "Session expired", nil)),
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "Session expired",
+		},
+		{name: "plain error", err: stderrors.New("boom"), wantCode: codes.Internal, wantMessage: "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(statusError(tt.err))
+			if !ok {
+				t.Fatalf("statusError(%v) is not a gRPC status", tt.err)
+			}
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("status = %s %q, want %s %q", st.Code(), st.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestSessionContext(t *testing.T) {
+
+	s := &AuthServer{TrustedProxies: utils.NewTrustedProxies([]string{"10.0.0.0/8"})}
+
+	tests := []struct {
+		name         string
+		peerAddr     string
+		forwardedFor string
+		wantIP       string
+	}{
+		{name: "direct client", peerAddr: "203.0.113.7:5000", wantIP: "203.0.113.7"},
+		{name: "direct client with forwarded header", peerAddr: "203.0.113.7:5000", forwardedFor: "198.51.100.1", wantIP: "203.0.113.7"},
+		{name: "behind trusted proxy", peerAddr: "10.0.0.2:5000", forwardedFor: "198.51.100.1", wantIP: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			addr, err := net.ResolveTCPAddr("tcp", tt.peerAddr)
+			if err != nil {
+				t.Fatalf("ResolveTCPAddr: %v", err)
+			}
+			md := metadata.Pairs("user-agent", "grpc-test")
+			if tt.forwardedFor != "" {
+				md.Set("x-forwarded-for", tt.forwardedFor)
+			}
+			ctx := metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: addr}), md)
+
+			ctx = s.sessionContext(ctx)
+			if got, _ := ctx.Value(ctxkeys.IPAddressKey).(string); got != tt.wantIP {
+				t.Errorf("IP address = %q, want %q", got, tt.wantIP)
+			}
+			if got, _ := ctx.Value(ctxkeys.UserAgentKey).(string); got != "grpc-test" {
+				t.Errorf("user agent = %q, want grpc-test", got)
+			}
+		})
+	}
+}
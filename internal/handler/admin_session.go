@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AdminSessionHandler struct {
+	AdminSessionService *service.AdminSessionService
+}
+
+func NewAdminSessionHandler(adminSessionService *service.AdminSessionService) *AdminSessionHandler {
+	return &AdminSessionHandler{
+		AdminSessionService: adminSessionService,
+	}
+}
+
+// SearchSessions godoc
+// @Summary      Поиск сессий пользователей
+// @Description  Ищет сессии по ID пользователя, IP адресу или подстроке User-Agent, последние обновленные первыми.
+// @Description  Без include_revoked возвращаются только активные сессии. Требует разрешение sessions:read.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization    header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Param        user_id          query     string  false  "ID пользователя"
+// @Param        ip               query     string  false  "IP адрес"
+// @Param        user_agent       query     string  false  "Подстрока User-Agent без учета регистра"
+// @Param        include_revoked  query     bool    false  "Включить отозванные и замененные сессии"
+// @Param        limit            query     int     false  "Не больше 200, по умолчанию 50"
+// @Param        offset           query     int     false  "Смещение"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/sessions [get]
+func (h *AdminSessionHandler) SearchSessions(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	search := service.SessionSearch{
+		IPAddress: query.Get("ip"),
+		UserAgent: query.Get("user_agent"),
+	}
+
+	var err error
+	if userID := query.Get("user_id"); userID != "" {
+		if search.UserID, err = uuid.Parse(userID); err != nil {
+			WriteTypeError(w, errors.ErrorTypeValidation, "Invalid user ID")
+			return
+		}
+	}
+	if includeRevoked := query.Get("include_revoked"); includeRevoked != "" {
+		if search.IncludeRevoked, err = strconv.ParseBool(includeRevoked); err != nil {
+			WriteTypeError(w, errors.ErrorTypeValidation, "Invalid include_revoked")
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if search.Limit, err = strconv.Atoi(limit); err != nil {
+			WriteTypeError(w, errors.ErrorTypeValidation, "Invalid limit")
+			return
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if search.Offset, err = strconv.Atoi(offset); err != nil {
+			WriteTypeError(w, errors.ErrorTypeValidation, "Invalid offset")
+			return
+		}
+	}
+
+	sessions, err := h.AdminSessionService.SearchSessions(r.Context(), search)
+	if err != nil {
+		slog.Error("Failed to search sessions", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// GetSession godoc
+// @Summary      Сессия пользователя
+// @Description  Возвращает сессию по ID, в том числе отозванную. Требует разрешение sessions:read.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        session_id     path      string  true  "ID сессии"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /admin/sessions/{session_id} [get]
+func (h *AdminSessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+
+	sessionID := chi.URLParam(r, "session_id")
+	session, err := h.AdminSessionService.GetSession(r.Context(), sessionID)
+	if err != nil {
+		slog.Error("Failed to get session", "session_id", sessionID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, session)
+}
+
+// RevokeSession godoc
+// @Summary      Принудительно завершить сессию
+// @Description  Отзывает все сессии семейства токенов и блокирует их действующие access token. Требует разрешение sessions:revoke.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        session_id     path      string  true  "ID сессии"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /admin/sessions/{session_id} [delete]
+func (h *AdminSessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+
+	sessionID := chi.URLParam(r, "session_id")
+	if err := h.AdminSessionService.RevokeSession(r.Context(), sessionID); err != nil {
+		slog.Error("Failed to revoke session", "session_id", sessionID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	slog.Info("Session revoked by admin", "session_id", sessionID)
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeUserSessions godoc
+// @Summary      Принудительно завершить все сессии пользователя
+// @Description  Отзывает все сессии пользователя и блокирует их действующие access token. Требует разрешение sessions:revoke.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        user_id        path      string  true  "ID пользователя"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/users/{user_id}/sessions [delete]
+func (h *AdminSessionHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid user ID")
+		return
+	}
+
+	count, err := h.AdminSessionService.RevokeUserSessions(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to revoke user sessions", "user_id", userID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	slog.Info("User sessions revoked by admin", "user_id", userID, "revoked", count)
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Sessions revoked successfully",
+		"revoked": count,
+	})
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"authservice/internal/authctx"
+	"authservice/internal/config"
 	"authservice/internal/errors"
 	"authservice/internal/service"
+	"authservice/internal/utils"
+	"encoding/json"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -17,61 +21,261 @@ import (
 )
 
 type AuthHandler struct {
-	AuthService *service.AuthService
+	AuthService    *service.AuthService
+	UserService    *service.UserService
+	MFAService     *service.MFAService
+	Tokens         *utils.TokenIssuer
+	Cookie         config.CookieConfig
+	TrustedProxies utils.TrustedProxies
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, userService *service.UserService, mfaService *service.MFAService, tokens *utils.TokenIssuer, cookie config.CookieConfig, trustedProxies utils.TrustedProxies) *AuthHandler {
 	return &AuthHandler{
-		AuthService: authService,
+		AuthService:    authService,
+		UserService:    userService,
+		MFAService:     mfaService,
+		Tokens:         tokens,
+		Cookie:         cookie,
+		TrustedProxies: trustedProxies,
 	}
 }
 
-// NewSession godoc
-// @Summary      Создать новую сессию
-// @Description  Возвращает access token в заголовке и refresh token в cookie
+func authenticatedClaims(r *http.Request) (*authctx.Claims, error) {
+	claims, ok := authctx.FromContext(r.Context())
+	if !ok {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "Access token required", nil)
+	}
+	return claims, nil
+}
+
+func authenticatedUserID(r *http.Request) (uuid.UUID, error) {
+	claims, err := authenticatedClaims(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+type Credentials struct {
+	Email    string `json:"email" example:"user@example.com"`
+	Password string `json:"password" example:"correct-horse-battery"`
+}
+
+type UserInfoResponse struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+func sessionContext(r *http.Request, trustedProxies utils.TrustedProxies) context.Context {
+	ipAddress := trustedProxies.ClientIP(r.RemoteAddr, strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+	ctx := context.WithValue(r.Context(), ctxkeys.UserAgentKey, r.Header.Get("User-Agent"))
+	return context.WithValue(ctx, ctxkeys.IPAddressKey, ipAddress)
+}
+
+func writeSessionTokens(w http.ResponseWriter, cookie config.CookieConfig, tokens *utils.TokenIssuer, accessToken, refreshToken string) {
+
+	w.Header().Set("Access-Token", "Bearer "+accessToken)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookie.Name,
+		Value:    refreshToken,
+		Path:     cookie.Path,
+		Domain:   cookie.Domain,
+		HttpOnly: true,
+		Secure:   cookie.Secure,
+		SameSite: cookie.SameSiteMode(),
+		Expires:  time.Now().Add(tokens.RefreshTokenTTL),
+	})
+
+	// Browser redirects such as the OAuth authorization request and
+	// forward-auth cannot send the header. They come from other sites, so
+	// the cookie is Lax.
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookie.AccessTokenName,
+		Value:    accessToken,
+		Path:     "/",
+		Domain:   cookie.Domain,
+		HttpOnly: true,
+		Secure:   cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(tokens.AccessTokenTTL),
+	})
+}
+
+func clearAccessTokenCookie(w http.ResponseWriter, cookie config.CookieConfig) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookie.AccessTokenName,
+		Path:     "/",
+		Domain:   cookie.Domain,
+		HttpOnly: true,
+		Secure:   cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+func decodeCredentials(r *http.Request) (Credentials, error) {
+	var credentials Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		return credentials, errors.NewError(errors.ErrorTypeValidation, "Invalid request body", err)
+	}
+	if credentials.Email == "" || credentials.Password == "" {
+		return credentials, errors.NewError(errors.ErrorTypeValidation, "Email and password are required", nil)
+	}
+	return credentials, nil
+}
+
+// Register godoc
+// @Summary      Регистрация пользователя
+// @Description  Создает пользователя с email и паролем
 // @Tags         auth
-// @Param        user_id           path      string  true   "User ID"           default(0921ac27-5ec4-4031-a8f2-665e9c3c9eb3)
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body      handler.Credentials  true  "Email и пароль"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      409  {object}  handler.Response
+// @Router       /register [post]
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+
+	credentials, err := decodeCredentials(r)
+	if err != nil {
+		slog.Error("Invalid registration request", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	user, err := h.UserService.Register(r.Context(), credentials.Email, credentials.Password)
+	if err != nil {
+		slog.Error("Failed to register user", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"user_id": user.ID,
+	})
+}
+
+// Login godoc
+// @Summary      Вход по email и паролю
+// @Description  Возвращает access token в заголовке и refresh token в cookie.
+// @Description  Если у пользователя включена TOTP, вместо сессии возвращается mfa_token для /login/mfa.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials       body      handler.Credentials  true   "Email и пароль"
 // @Param        User-Agent        header    string  false  "User-Agent"        default(Swagger-Test)
 // @Param        X-Forwarded-For   header    string  false  "IP адрес клиента"  default(127.0.0.1)
 // @Success      200  {object}  handler.SuccessResponse
 // @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
 // @Header       200  {string}  Access-Token  "Bearer <access_token>"
 // @Set-Cookie   refresh_token=...; Path=/refresh; HttpOnly; Secure; SameSite=Strict
-// @Router       /new_session/{user_id} [get]
-func (h *AuthHandler) NewSession(w http.ResponseWriter, r *http.Request) {
+// @Router       /login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
-	strID := chi.URLParam(r, "user_id")
-	id, err := uuid.Parse(strID)
+	credentials, err := decodeCredentials(r)
 	if err != nil {
-		slog.Error("Invalid user ID", "user_id", strID, "error", err)
-		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid user ID format")
+		slog.Error("Invalid login request", "error", err)
+		WriteError(w, err)
 		return
 	}
 
-	userAgent := r.Header.Get("User-Agent")
-	ip := r.Header.Get("X-Forwarded-For")
+	user, err := h.UserService.Authenticate(r.Context(), credentials.Email, credentials.Password)
+	if err != nil {
+		slog.Error("Failed to authenticate user", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	mfaEnabled, err := h.MFAService.IsTOTPEnabled(r.Context(), user.ID)
+	if err != nil {
+		slog.Error("Failed to check second factor", "error", err)
+		WriteError(w, err)
+		return
+	}
 
-	ctx := context.WithValue(r.Context(), ctxkeys.UserAgentKey, userAgent)
-	ctx = context.WithValue(ctx, ctxkeys.IPAddressKey, ip)
+	if mfaEnabled {
+		mfaToken, err := h.Tokens.GenerateMFAToken(user.ID.String())
+		if err != nil {
+			slog.Error("Failed to create MFA token", "error", err)
+			WriteError(w, err)
+			return
+		}
+
+		WriteSuccess(w, map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
 
-	accessToken, refreshToken, err := h.AuthService.NewSession(ctx, id)
+	accessToken, refreshToken, err := h.AuthService.NewSession(sessionContext(r, h.TrustedProxies), user.ID, []string{service.AMRPassword})
 	if err != nil {
 		slog.Error("Failed to create new session", "error", err)
 		WriteError(w, err)
 		return
 	}
 
-	w.Header().Set("Access-Token", "Bearer "+accessToken)
+	writeSessionTokens(w, h.Cookie, h.Tokens, accessToken, refreshToken)
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Path:     "/refresh",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Session created successfully",
 	})
+}
+
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// LoginMFA godoc
+// @Summary      Второй шаг входа с TOTP
+// @Description  Принимает mfa_token из /login и TOTP код или код восстановления. mfa_token одноразовый и допускает не более 5 попыток,
+// @Description  после 10 неудачных попыток второй шаг входа блокируется на 15 минут.
+// @Description  Возвращает access token в заголовке и refresh token в cookie.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request           body      handler.MFALoginRequest  true   "MFA token и код"
+// @Param        User-Agent        header    string  false  "User-Agent"        default(Swagger-Test)
+// @Param        X-Forwarded-For   header    string  false  "IP адрес клиента"  default(127.0.0.1)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Header       200  {string}  Access-Token  "Bearer <access_token>"
+// @Set-Cookie   refresh_token=...; Path=/refresh; HttpOnly; Secure; SameSite=Strict
+// @Router       /login/mfa [post]
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+
+	var request MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Error("Invalid MFA login request", "error", err)
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid request body")
+		return
+	}
+	if request.MFAToken == "" || (request.Code == "" && request.RecoveryCode == "") {
+		WriteTypeError(w, errors.ErrorTypeValidation, "MFA token and code are required")
+		return
+	}
+
+	userID, amr, err := h.MFAService.CompleteLogin(r.Context(), request.MFAToken, request.Code, request.RecoveryCode)
+	if err != nil {
+		slog.Error("Failed to verify second factor", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	accessToken, refreshToken, err := h.AuthService.NewSession(sessionContext(r, h.TrustedProxies), userID, amr)
+	if err != nil {
+		slog.Error("Failed to create new session", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	writeSessionTokens(w, h.Cookie, h.Tokens, accessToken, refreshToken)
 
 	WriteSuccess(w, map[string]interface{}{
 		"message": "Session created successfully",
@@ -91,38 +295,58 @@ func (h *AuthHandler) NewSession(w http.ResponseWriter, r *http.Request) {
 // @Router       /me [get]
 func (h *AuthHandler) GetAuthenticatedUserID(w http.ResponseWriter, r *http.Request) {
 
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		slog.Error("Missing access token header")
-		WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		slog.Error("Failed to get user ID from token", "error", err)
+		WriteError(w, err)
 		return
 	}
 
-	accessToken := strings.Split(authHeader, " ")[1]
-	if accessToken == "" {
-		slog.Error("Missing access token header")
-		WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
+	WriteSuccess(w, map[string]interface{}{
+		"user_id": userID,
+	})
+}
+
+// UserInfo godoc
+// @Summary      OpenID Connect userinfo
+// @Description  Возвращает claims владельца access token: sub и, при scope email, email. Требует scope openid.
+// @Tags         oauth
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.UserInfoResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /userinfo [get]
+func (h *AuthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		slog.Error("Failed to get user ID from token", "error", err)
+		WriteError(w, err)
 		return
 	}
 
-	userID, err := h.AuthService.GetUserID(r.Context(), accessToken)
+	user, err := h.UserService.GetUser(r.Context(), userID)
 	if err != nil {
-		slog.Error("Failed to get user ID from token", "error", err)
+		slog.Error("Failed to get user", "user_id", userID, "error", err)
 		WriteError(w, err)
 		return
 	}
 
-	WriteSuccess(w, map[string]interface{}{
-		"user_id": userID,
-	})
+	response := UserInfoResponse{Subject: user.ID.String()}
+	if claims, ok := authctx.FromContext(r.Context()); ok && claims.HasScope(service.ScopeEmail) {
+		response.Email = user.Email
+	}
+
+	writeOAuthJSON(w, http.StatusOK, response)
 }
 
 // RefreshSession godoc
 // @Summary      Обновить access/refresh токены
-// @Description  Требует access token в заголовке Authorization и cookie refresh_token.
+// @Description  Требует cookie refresh_token. Access token в заголовке Authorization необязателен и может быть просрочен.
 // @Tags         auth
 // @Produce      json
-// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Param        Authorization      header    string  false  "Bearer access_token"  default(Bearer <access_token>)
 // @Param        User-Agent         header    string  false  "User-Agent"           default(Swagger-Test)
 // @Param        X-Forwarded-For    header    string  false  "IP адрес клиента"     default(127.0.0.1)
 // @Success      200  {object}  handler.SuccessResponse
@@ -132,14 +356,9 @@ func (h *AuthHandler) GetAuthenticatedUserID(w http.ResponseWriter, r *http.Requ
 // @Router       /refresh [get]
 func (h *AuthHandler) RefreshSession(w http.ResponseWriter, r *http.Request) {
 
-	accessToken := strings.Split(r.Header.Get("Authorization"), " ")[1]
-	if accessToken == "" {
-		slog.Error("Missing access token header")
-		WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
-		return
-	}
+	accessToken := authctx.BearerToken(r)
 
-	cookie, err := r.Cookie("refresh_token")
+	cookie, err := r.Cookie(h.Cookie.Name)
 	if err != nil {
 		slog.Error("Missing refresh token cookie", "error", err)
 		WriteTypeError(w, errors.ErrorTypeAuth, "Refresh token required")
@@ -147,30 +366,14 @@ func (h *AuthHandler) RefreshSession(w http.ResponseWriter, r *http.Request) {
 	}
 	refreshToken := cookie.Value
 
-	userAgent := r.Header.Get("User-Agent")
-	ip := r.Header.Get("X-Forwarded-For")
-
-	ctx := context.WithValue(r.Context(), ctxkeys.UserAgentKey, userAgent)
-	ctx = context.WithValue(ctx, ctxkeys.IPAddressKey, ip)
-
-	newAccessToken, newRefreshToken, err := h.AuthService.RefreshSession(ctx, accessToken, refreshToken)
+	newAccessToken, newRefreshToken, err := h.AuthService.RefreshSession(sessionContext(r, h.TrustedProxies), accessToken, refreshToken)
 	if err != nil {
 		slog.Error("Failed to refresh session", "error", err)
 		WriteError(w, err)
 		return
 	}
 
-	w.Header().Set("Access-Token", "Bearer "+newAccessToken)
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    newRefreshToken,
-		Path:     "/refresh",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-	})
+	writeSessionTokens(w, h.Cookie, h.Tokens, newAccessToken, newRefreshToken)
 
 	WriteSuccess(w, map[string]interface{}{
 		"message": "Session refreshed successfully",
@@ -190,21 +393,14 @@ func (h *AuthHandler) RefreshSession(w http.ResponseWriter, r *http.Request) {
 // @Router       /refresh/revoke [post]
 func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
 
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		slog.Error("Missing access token header")
-		WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
-		return
-	}
-
-	accessToken := strings.Split(authHeader, " ")[1]
+	accessToken := authctx.BearerToken(r)
 	if accessToken == "" {
 		slog.Error("Missing access token header")
 		WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
 		return
 	}
 
-	cookie, err := r.Cookie("refresh_token")
+	cookie, err := r.Cookie(h.Cookie.Name)
 	if err != nil {
 		slog.Error("Missing refresh token cookie", "error", err)
 		WriteTypeError(w, errors.ErrorTypeAuth, "Refresh token required")
@@ -219,6 +415,103 @@ func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	clearAccessTokenCookie(w, h.Cookie)
+
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions godoc
+// @Summary      Отозвать все сессии пользователя
+// @Description  Завершает все refresh-сессии владельца access token и блокирует их access tokens.
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Router       /refresh/revoke_all [post]
+func (h *AuthHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	revoked, err := h.AuthService.RevokeAllSessions(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to revoke all sessions", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"message":          "All sessions revoked successfully",
+		"revoked_sessions": revoked,
+	})
+}
+
+// ListSessions godoc
+// @Summary      Список активных сессий
+// @Description  Возвращает активные сессии владельца access token, текущая сессия отмечена флагом current.
+// @Tags         sessions
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Router       /sessions [get]
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+
+	claims, err := authenticatedClaims(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	sessions, err := h.AuthService.ListSessions(r.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		slog.Error("Failed to list sessions", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// RevokeUserSession godoc
+// @Summary      Отозвать сессию по ID
+// @Description  Завершает одну из сессий владельца access token.
+// @Tags         sessions
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Param        session_id         path      string  true   "Session ID"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /sessions/{session_id} [delete]
+func (h *AuthHandler) RevokeUserSession(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "session_id")
+	if sessionID == "" {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Session ID required")
+		return
+	}
+
+	if err := h.AuthService.RevokeUserSession(r.Context(), userID, sessionID); err != nil {
+		slog.Error("Failed to revoke session", "session_id", sessionID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
 	WriteSuccess(w, map[string]interface{}{
 		"message": "Session revoked successfully",
 	})
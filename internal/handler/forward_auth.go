@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"authservice/internal/authctx"
+	"authservice/internal/config"
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultForwardAuthRedirectParam = "rd"
+
+type ForwardAuthHandler struct {
+	AuthService       *service.AuthService
+	Config            config.ForwardAuthConfig
+	AccessTokenCookie string
+	TrustedProxies    utils.TrustedProxies
+}
+
+func NewForwardAuthHandler(authService *service.AuthService, forwardAuth config.ForwardAuthConfig, cookie config.CookieConfig, trustedProxies utils.TrustedProxies) *ForwardAuthHandler {
+	return &ForwardAuthHandler{
+		AuthService:       authService,
+		Config:            forwardAuth,
+		AccessTokenCookie: cookie.AccessTokenName,
+		TrustedProxies:    trustedProxies,
+	}
+}
+
+func (h *ForwardAuthHandler) upstream(r *http.Request) config.ForwardAuthUpstream {
+
+	upstream := h.Config.Default
+	if configured, ok := h.Config.Upstreams[h.forwardedHost(r)]; ok {
+		upstream = configured
+	}
+
+	if upstream.CookieName == "" {
+		upstream.CookieName = h.AccessTokenCookie
+	}
+	if upstream.RedirectParam == "" {
+		upstream.RedirectParam = defaultForwardAuthRedirectParam
+	}
+
+	return upstream
+}
+
+func (h *ForwardAuthHandler) forwardedHeader(r *http.Request, name string) string {
+	if !h.TrustedProxies.Trusts(r.RemoteAddr) {
+		return ""
+	}
+	return r.Header.Get(name)
+}
+
+// Traefik and Envoy set X-Forwarded-Host, nginx auth_request keeps the
+// original Host.
+func (h *ForwardAuthHandler) forwardedHost(r *http.Request) string {
+
+	host := h.forwardedHeader(r, "X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	return strings.ToLower(host)
+}
+
+func (h *ForwardAuthHandler) originalURL(r *http.Request) string {
+
+	scheme := h.forwardedHeader(r, "X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "https"
+	}
+	host := h.forwardedHeader(r, "X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	uri := h.forwardedHeader(r, "X-Forwarded-Uri")
+	if uri == "" {
+		uri = h.forwardedHeader(r, "X-Original-URI")
+	}
+	if uri == "" {
+		uri = "/"
+	}
+
+	return scheme + "://" + host + uri
+}
+
+func (h *ForwardAuthHandler) isBrowserRequest(r *http.Request) bool {
+
+	method := h.forwardedHeader(r, "X-Forwarded-Method")
+	if method == "" {
+		method = r.Method
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// ForwardAuth godoc
+// @Summary      Проверка запроса для reverse proxy
+// @Description  Проверяет access token из заголовка Authorization или cookie так же, как защищенные маршруты сервиса.
+// @Description  Используется как forward-auth в Traefik, auth_request в nginx или ext_authz в Envoy, принимает любой HTTP метод.
+// @Description  Настройки (audience, страница входа, cookie, обязательные scope и роли) выбираются по X-Forwarded-Host.
+// @Description  Заголовки X-Forwarded-* учитываются только от адресов из forward_auth.trusted_proxies.
+// @Description  При успехе возвращает заголовки X-User-Id, X-Session-Id и X-Scopes. Запросы браузера без токена перенаправляются на страницу входа.
+// @Tags         auth
+// @Param        Authorization       header  string  false  "Bearer access_token"
+// @Param        X-Forwarded-Host    header  string  false  "Хост upstream"
+// @Param        X-Forwarded-Proto   header  string  false  "Схема исходного запроса"
+// @Param        X-Forwarded-Uri     header  string  false  "Путь исходного запроса"
+// @Param        X-Forwarded-Method  header  string  false  "Метод исходного запроса"
+// @Success      200
+// @Header       200  {string}  X-User-Id     "ID пользователя"
+// @Header       200  {string}  X-Session-Id  "ID сессии"
+// @Header       200  {string}  X-Scopes      "Scope токена через пробел"
+// @Failure      302
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /forward-auth [get]
+func (h *ForwardAuthHandler) ForwardAuth(w http.ResponseWriter, r *http.Request) {
+
+	upstream := h.upstream(r)
+
+	accessToken := authctx.BearerToken(r)
+	if accessToken == "" {
+		if cookie, err := r.Cookie(upstream.CookieName); err == nil {
+			accessToken = cookie.Value
+		}
+	}
+
+	var claims *authctx.Claims
+	var err error
+	if upstream.Audience != "" {
+		claims, err = h.AuthService.VerifyAccessTokenFor(accessToken, upstream.Audience)
+	} else {
+		claims, err = h.AuthService.VerifyAccessToken(accessToken)
+	}
+	if err != nil {
+		slog.Info("Forward-auth request rejected", "host", h.forwardedHost(r), "error", err)
+
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeAuth && upstream.LoginURL != "" && h.isBrowserRequest(r) {
+			loginURL, parseErr := url.Parse(upstream.LoginURL)
+			if parseErr != nil {
+				slog.Error("Invalid forward-auth login URL", "login_url", upstream.LoginURL, "error", parseErr)
+				WriteError(w, err)
+				return
+			}
+			query := loginURL.Query()
+			query.Set(upstream.RedirectParam, h.originalURL(r))
+			loginURL.RawQuery = query.Encode()
+
+			http.Redirect(w, r, loginURL.String(), http.StatusFound)
+			return
+		}
+
+		WriteError(w, err)
+		return
+	}
+
+	for _, scope := range upstream.Scopes {
+		if !claims.HasScope(scope) {
+			WriteTypeError(w, errors.ErrorTypeForbidden, "Insufficient scope")
+			return
+		}
+	}
+	for _, role := range upstream.Roles {
+		if !claims.HasRole(role) {
+			WriteTypeError(w, errors.ErrorTypeForbidden, "Insufficient role")
+			return
+		}
+	}
+
+	w.Header().Set("X-User-Id", claims.UserID.String())
+	w.Header().Set("X-Session-Id", claims.SessionID)
+	w.Header().Set("X-Scopes", strings.Join(claims.Scopes, " "))
+	w.WriteHeader(http.StatusOK)
+}
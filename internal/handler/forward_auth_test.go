@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"authservice/internal/config"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testProxyAddr  = "10.0.0.2:41000"
+	testClientAddr = "203.0.113.7:52000"
+	adminHost      = "admin.example.com"
+	adminAudience  = "https://admin.example.com"
+	adminLoginURL  = "https://auth.example.com/login"
+)
+
+func newTestForwardAuthHandler(t *testing.T) (*ForwardAuthHandler, *utils.TokenIssuer) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.JWT.Audience = "https://auth.example.com"
+	cfg.ForwardAuth.TrustedProxies = []string{"10.0.0.0/8"}
+	cfg.ForwardAuth.Upstreams = map[string]config.ForwardAuthUpstream{
+		adminHost: {Audience: adminAudience, LoginURL: adminLoginURL, Roles: []string{service.RoleAdmin}},
+	}
+	tokens := utils.NewTokenIssuer(cfg, utils.NewKeyring(utils.NewHMACSigningKey("test", []byte("test-secret"))))
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	authService := service.NewAuthService(nil, nil, service.NewBlacklistService(redisClient, cfg.JWT.AccessTokenTTL), tokens, cfg.Webhook)
+
+	return NewForwardAuthHandler(authService, cfg.ForwardAuth, cfg.Cookie, utils.NewTrustedProxies(cfg.ForwardAuth.TrustedProxies)), tokens
+}
+
+func TestForwardAuth(t *testing.T) {
+
+	h, tokens := newTestForwardAuthHandler(t)
+
+	newToken := func(audience string, roles ...string) string {
+		token, err := tokens.GenerateJWT(utils.AccessTokenClaims{
+			UserID:    uuid.NewString(),
+			SessionID: uuid.NewString(),
+			Scope:     service.FirstPartyScope,
+			Audience:  []string{audience},
+			Roles:     roles,
+		})
+		if err != nil {
+			t.Fatalf("GenerateJWT: %v", err)
+		}
+		return token
+	}
+	ownToken := newToken(tokens.Audience)
+	adminToken := newToken(adminAudience, service.RoleAdmin)
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedHost string
+		token         string
+		browser       bool
+		wantStatus    int
+		wantRedirect  string
+	}{
+		{name: "upstream audience", remoteAddr: testProxyAddr, forwardedHost: adminHost, token: adminToken, wantStatus: http.StatusOK},
+		{name: "upstream host with port and upper case", remoteAddr: testProxyAddr, forwardedHost: "Admin.Example.com:443", token: adminToken, wantStatus: http.StatusOK},
+		{name: "own audience on upstream", remoteAddr: testProxyAddr, forwardedHost: adminHost, token: ownToken, wantStatus: http.StatusUnauthorized},
+		{name: "upstream role missing", remoteAddr: testProxyAddr, forwardedHost: adminHost, token: newToken(adminAudience), wantStatus: http.StatusForbidden},
+		{name: "default upstream", remoteAddr: testProxyAddr, forwardedHost: "app.example.com", token: ownToken, wantStatus: http.StatusOK},
+		{name: "untrusted client picks upstream", remoteAddr: testClientAddr, forwardedHost: adminHost, token: adminToken, wantStatus: http.StatusUnauthorized},
+		{name: "untrusted client gets default", remoteAddr: testClientAddr, forwardedHost: adminHost, token: ownToken, wantStatus: http.StatusOK},
+		{name: "missing token", remoteAddr: testProxyAddr, forwardedHost: adminHost, wantStatus: http.StatusUnauthorized},
+		{
+			name:          "browser without token",
+			remoteAddr:    testProxyAddr,
+			forwardedHost: adminHost,
+			browser:       true,
+			wantStatus:    http.StatusFound,
+			wantRedirect:  "https://admin.example.com/reports",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := httptest.NewRequest(http.MethodGet, "/forward-auth", nil)
+			r.Host = "auth.example.com"
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("X-Forwarded-Host", tt.forwardedHost)
+			r.Header.Set("X-Forwarded-Proto", "https")
+			r.Header.Set("X-Forwarded-Uri", "/reports")
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.browser {
+				r.Header.Set("Accept", "text/html")
+			}
+
+			w := httptest.NewRecorder()
+			h.ForwardAuth(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantRedirect != "" {
+				location, err := url.Parse(w.Header().Get("Location"))
+				if err != nil {
+					t.Fatalf("parse Location: %v", err)
+				}
+				if got := location.Query().Get(defaultForwardAuthRedirectParam); got != tt.wantRedirect {
+					t.Errorf("redirect target = %q, want %q", got, tt.wantRedirect)
+				}
+			}
+			if tt.wantStatus == http.StatusOK && w.Header().Get("X-User-Id") == "" {
+				t.Error("missing X-User-Id")
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"authservice/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+type KeyHandler struct {
+	KeyService *service.KeyService
+}
+
+func NewKeyHandler(keyService *service.KeyService) *KeyHandler {
+	return &KeyHandler{
+		KeyService: keyService,
+	}
+}
+
+// RotateKeys godoc
+// @Summary      Ротация ключа подписи
+// @Description  Создает новый ключ подписи и сохраняет его в базе данных. Ключ сразу публикуется в JWKS и начинает подписывать токены через минуту,
+// @Description  прежний ключ принимается до истечения выпущенных им токенов.
+// @Description  Требует разрешение keys:rotate.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/keys/rotate [post]
+func (h *KeyHandler) RotateKeys(w http.ResponseWriter, r *http.Request) {
+
+	keyID, err := h.KeyService.Rotate(r.Context())
+	if err != nil {
+		slog.Error("Failed to rotate signing key", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Signing key rotated successfully",
+		"kid":     keyID,
+	})
+}
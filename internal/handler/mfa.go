@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+type MFAHandler struct {
+	AuthService *service.AuthService
+	MFAService  *service.MFAService
+}
+
+func NewMFAHandler(authService *service.AuthService, mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		AuthService: authService,
+		MFAService:  mfaService,
+	}
+}
+
+type TOTPConfirmRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// EnrollTOTP godoc
+// @Summary      Начать подключение TOTP
+// @Description  Возвращает секрет и otpauth:// URI для приложения-аутентификатора.
+// @Tags         mfa
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      409  {object}  handler.Response
+// @Router       /mfa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		slog.Error("Failed to authenticate TOTP enrollment", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	enrollment, err := h.MFAService.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to enroll TOTP", "user_id", userID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Подтвердить подключение TOTP
+// @Description  Принимает первый код из приложения и возвращает одноразовые коды восстановления.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Param        request            body      handler.TOTPConfirmRequest  true  "TOTP код"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		slog.Error("Failed to authenticate TOTP confirmation", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	var request TOTPConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Code == "" {
+		WriteTypeError(w, errors.ErrorTypeValidation, "TOTP code required")
+		return
+	}
+
+	recoveryCodes, err := h.MFAService.ConfirmTOTP(r.Context(), userID, request.Code)
+	if err != nil {
+		slog.Error("Failed to confirm TOTP", "user_id", userID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"message":        "TOTP enabled successfully",
+		"recovery_codes": recoveryCodes,
+	})
+}
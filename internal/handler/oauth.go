@@ -0,0 +1,639 @@
+package handler
+
+import (
+	"authservice/internal/authctx"
+	"authservice/internal/config"
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OAuthHandler struct {
+	OAuthService   *service.OAuthService
+	AuthService    *service.AuthService
+	OIDC           config.OIDCConfig
+	Cookie         config.CookieConfig
+	TrustedProxies utils.TrustedProxies
+}
+
+func NewOAuthHandler(oauthService *service.OAuthService, authService *service.AuthService, oidc config.OIDCConfig, cookie config.CookieConfig, trustedProxies utils.TrustedProxies) *OAuthHandler {
+	return &OAuthHandler{
+		OAuthService:   oauthService,
+		AuthService:    authService,
+		OIDC:           oidc,
+		Cookie:         cookie,
+		TrustedProxies: trustedProxies,
+	}
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type ServiceAccountRequest struct {
+	Name      string   `json:"name" example:"billing-service"`
+	PublicKey string   `json:"public_key,omitempty" example:"-----BEGIN PUBLIC KEY-----..."`
+	Scopes    []string `json:"scopes" example:"billing:read"`
+	Audiences []string `json:"audiences" example:"https://billing.example.com"`
+}
+
+type RegisterClientRequest struct {
+	Name                   string   `json:"name" example:"Web app"`
+	RedirectURIs           []string `json:"redirect_uris" example:"https://app.example.com/callback"`
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris" example:"https://app.example.com/"`
+	Scopes                 []string `json:"scopes,omitempty" example:"openid,email"`
+	Audiences              []string `json:"audiences,omitempty" example:"https://api.example.com"`
+	Confidential           bool     `json:"confidential"`
+}
+
+// oauthErrorCode reports errors without a code as server_error, so internal
+// details are not leaked.
+func oauthErrorCode(err error) (string, string) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok {
+		return service.OAuthServerError, "Internal server error"
+	}
+	if appErr.Code != "" {
+		return appErr.Code, appErr.Message
+	}
+	switch appErr.Type {
+	case errors.ErrorTypeValidation:
+		return service.OAuthInvalidRequest, appErr.Message
+	case errors.ErrorTypeAuth:
+		return service.OAuthInvalidGrant, appErr.Message
+	default:
+		return service.OAuthServerError, "Internal server error"
+	}
+}
+
+func writeOAuthJSON(w http.ResponseWriter, statusCode int, data any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("Failed to encode OAuth response", "error", err)
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, err error) {
+
+	code, description := oauthErrorCode(err)
+
+	statusCode := http.StatusBadRequest
+	switch code {
+	case service.OAuthInvalidClient:
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		statusCode = http.StatusUnauthorized
+	case service.OAuthServerError:
+		statusCode = http.StatusInternalServerError
+	}
+
+	writeOAuthJSON(w, statusCode, OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+func clientCredentials(r *http.Request) service.ClientCredentials {
+
+	credentials := service.ClientCredentials{
+		ClientID:            r.PostForm.Get("client_id"),
+		ClientSecret:        r.PostForm.Get("client_secret"),
+		ClientAssertionType: r.PostForm.Get("client_assertion_type"),
+		ClientAssertion:     r.PostForm.Get("client_assertion"),
+	}
+
+	// RFC 6749 section 2.3.1: credentials in the Authorization header are
+	// form-encoded before they are joined.
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		credentials.ClientID, _ = url.QueryUnescape(clientID)
+		credentials.ClientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	return credentials
+}
+
+func authorizationRedirect(redirectURI, state, code string, err error) (string, error) {
+
+	u, parseErr := url.Parse(redirectURI)
+	if parseErr != nil {
+		return "", errors.NewError(errors.ErrorTypeValidation, "invalid redirect_uri", parseErr)
+	}
+
+	query := u.Query()
+	if err != nil {
+		errorCode, description := oauthErrorCode(err)
+		query.Set("error", errorCode)
+		query.Set("error_description", description)
+	} else {
+		query.Set("code", code)
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func authorizeRequest(query url.Values) service.AuthorizeRequest {
+	return service.AuthorizeRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		Nonce:               query.Get("nonce"),
+		Prompt:              query.Get("prompt"),
+	}
+}
+
+func (h *OAuthHandler) authorizeURL(rawQuery string) string {
+	return h.OAuthService.Tokens.Issuer + "/oauth/authorize?" + rawQuery
+}
+
+func interactionURL(page string, params url.Values) string {
+
+	u, err := url.Parse(page)
+	if err != nil {
+		return page
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Authorize godoc
+// @Summary      OAuth 2.0 авторизация
+// @Description  Выдает authorization code пользователю и перенаправляет на redirect_uri клиента.
+// @Description  Пользователь определяется по access token из заголовка Authorization или cookie access_token, которую ставит вход.
+// @Description  Без входа браузер перенаправляется на oidc.login_url, без согласия на запрошенные scope - на oidc.consent_url,
+// @Description  оба получают URL этого запроса в return_to. С prompt=none вместо этого возвращаются ошибки login_required и consent_required.
+// @Description  PKCE с методом S256 обязателен. Ошибки после проверки client_id и redirect_uri передаются в redirect_uri.
+// @Tags         oauth
+// @Param        Authorization          header    string  false  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        response_type          query     string  true   "code"
+// @Param        client_id              query     string  true   "ID клиента"
+// @Param        redirect_uri           query     string  true   "Зарегистрированный redirect URI"
+// @Param        code_challenge         query     string  true   "BASE64URL(SHA256(code_verifier))"
+// @Param        code_challenge_method  query     string  true   "S256"
+// @Param        scope                  query     string  false  "Запрашиваемые scope, openid для получения ID token"
+// @Param        nonce                  query     string  false  "Nonce, возвращается в ID token"
+// @Param        state                  query     string  false  "Состояние клиента"
+// @Param        prompt                 query     string  false  "none или consent"
+// @Success      302
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Router       /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+
+	request := authorizeRequest(r.URL.Query())
+
+	client, err := h.OAuthService.AuthorizeClient(r.Context(), request.ClientID, request.RedirectURI)
+	if err != nil {
+		slog.Error("Invalid authorization request", "client_id", request.ClientID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	accessToken := authctx.BearerToken(r)
+	if accessToken == "" {
+		if cookie, err := r.Cookie(h.Cookie.AccessTokenName); err == nil {
+			accessToken = cookie.Value
+		}
+	}
+
+	claims, err := h.AuthService.VerifyFirstPartyToken(accessToken)
+	if err != nil {
+		h.requireLogin(w, r, request, err)
+		return
+	}
+
+	code, err := h.OAuthService.Authorize(r.Context(), claims, client, request)
+	if err != nil {
+		errorCode, _ := oauthErrorCode(err)
+		if errorCode == service.OAuthConsentRequired && h.OIDC.ConsentURL != "" && !service.HasPrompt(request.Prompt, service.PromptNone) {
+			http.Redirect(w, r, interactionURL(h.OIDC.ConsentURL, url.Values{
+				"client_id":   {client.ClientID},
+				"client_name": {client.Name},
+				"scope":       {request.Scope},
+				"return_to":   {h.authorizeURL(r.URL.RawQuery)},
+			}), http.StatusFound)
+			return
+		}
+		slog.Error("Failed to authorize client", "client_id", request.ClientID, "error", err)
+	}
+
+	redirect, err := authorizationRedirect(request.RedirectURI, request.State, code, err)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (h *OAuthHandler) requireLogin(w http.ResponseWriter, r *http.Request, request service.AuthorizeRequest, err error) {
+
+	if service.HasPrompt(request.Prompt, service.PromptNone) {
+		loginRequired := errors.NewError(errors.ErrorTypeAuth, "user authentication required", err)
+		loginRequired.Code = service.OAuthLoginRequired
+
+		redirect, err := authorizationRedirect(request.RedirectURI, request.State, "", loginRequired)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	if h.OIDC.LoginURL == "" {
+		WriteError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, interactionURL(h.OIDC.LoginURL, url.Values{
+		"return_to": {h.authorizeURL(r.URL.RawQuery)},
+	}), http.StatusFound)
+}
+
+type ConsentRequest struct {
+	ReturnTo string `json:"return_to" example:"https://auth.example.com/oauth/authorize?client_id=..."`
+	Approved bool   `json:"approved"`
+}
+
+// Consent godoc
+// @Summary      Согласие пользователя на доступ клиента
+// @Description  Страница согласия передает return_to, полученный от /oauth/authorize, и ответ пользователя.
+// @Description  При согласии scope запроса сохраняются, и в redirect_to возвращается URL запроса авторизации.
+// @Description  При отказе в redirect_to возвращается redirect_uri клиента с ошибкой access_denied.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                   true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        request        body      handler.ConsentRequest  true  "Ответ пользователя"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Router       /oauth/consent [post]
+func (h *OAuthHandler) Consent(w http.ResponseWriter, r *http.Request) {
+
+	claims, err := authenticatedClaims(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var request ConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Error("Invalid consent request", "error", err)
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid request body")
+		return
+	}
+
+	returnTo, err := url.Parse(request.ReturnTo)
+	if err != nil || returnTo.Scheme+"://"+returnTo.Host+returnTo.Path != h.OAuthService.Tokens.Issuer+"/oauth/authorize" {
+		WriteTypeError(w, errors.ErrorTypeValidation, "return_to must be an authorization request")
+		return
+	}
+	query := returnTo.Query()
+	authorize := authorizeRequest(query)
+
+	client, err := h.OAuthService.AuthorizeClient(r.Context(), authorize.ClientID, authorize.RedirectURI)
+	if err != nil {
+		slog.Error("Invalid authorization request", "client_id", authorize.ClientID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	err = h.OAuthService.GrantConsent(r.Context(), claims, client, authorize, request.Approved)
+	if err != nil {
+		if errorCode, _ := oauthErrorCode(err); errorCode != service.OAuthAccessDenied {
+			slog.Error("Failed to save consent", "client_id", authorize.ClientID, "error", err)
+			WriteError(w, err)
+			return
+		}
+
+		redirect, err := authorizationRedirect(authorize.RedirectURI, authorize.State, "", err)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		WriteSuccess(w, map[string]interface{}{
+			"redirect_to": redirect,
+		})
+		return
+	}
+
+	// Without this the repeated request would ask for consent again.
+	prompts := slices.DeleteFunc(strings.Fields(authorize.Prompt), func(prompt string) bool {
+		return prompt == service.PromptConsent
+	})
+	if len(prompts) == 0 {
+		query.Del("prompt")
+	} else {
+		query.Set("prompt", strings.Join(prompts, " "))
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"redirect_to": h.authorizeURL(query.Encode()),
+	})
+}
+
+// Token godoc
+// @Summary      OAuth 2.0 token endpoint
+// @Description  Поддерживает grant_type authorization_code (с code_verifier), refresh_token и client_credentials.
+// @Description  Конфиденциальные клиенты передают секрет через HTTP Basic или client_secret,
+// @Description  клиенты с зарегистрированным публичным ключом - подписанный client_assertion (private_key_jwt).
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type             formData  string  true   "authorization_code, refresh_token или client_credentials"
+// @Param        client_id              formData  string  false  "ID клиента, если не передан через HTTP Basic"
+// @Param        client_secret          formData  string  false  "Секрет конфиденциального клиента"
+// @Param        client_assertion_type  formData  string  false  "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param        client_assertion       formData  string  false  "JWT, подписанный ключом клиента"
+// @Param        code                   formData  string  false  "Authorization code"
+// @Param        redirect_uri           formData  string  false  "redirect_uri из запроса авторизации"
+// @Param        code_verifier          formData  string  false  "PKCE code_verifier"
+// @Param        refresh_token          formData  string  false  "Refresh token"
+// @Param        scope                  formData  string  false  "Запрашиваемые scope для client_credentials, по умолчанию все разрешенные клиенту"
+// @Param        User-Agent             header    string  false  "User-Agent"        default(Swagger-Test)
+// @Param        X-Forwarded-For        header    string  false  "IP адрес клиента"  default(127.0.0.1)
+// @Success      200  {object}  service.TokenResponse
+// @Failure      400  {object}  handler.OAuthErrorResponse
+// @Failure      401  {object}  handler.OAuthErrorResponse
+// @Router       /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, errors.NewError(errors.ErrorTypeValidation, "Invalid request body", err))
+		return
+	}
+
+	request := service.TokenRequest{
+		ClientCredentials: clientCredentials(r),
+		GrantType:         r.PostForm.Get("grant_type"),
+		Code:              r.PostForm.Get("code"),
+		RedirectURI:       r.PostForm.Get("redirect_uri"),
+		CodeVerifier:      r.PostForm.Get("code_verifier"),
+		RefreshToken:      r.PostForm.Get("refresh_token"),
+		Scope:             r.PostForm.Get("scope"),
+	}
+
+	response, err := h.OAuthService.Token(sessionContext(r, h.TrustedProxies), request)
+	if err != nil {
+		slog.Error("Failed to issue OAuth tokens", "client_id", request.ClientID, "grant_type", request.GrantType, "error", err)
+		writeOAuthError(w, err)
+		return
+	}
+
+	writeOAuthJSON(w, http.StatusOK, response)
+}
+
+// Introspect godoc
+// @Summary      Интроспекция токена (RFC 7662)
+// @Description  Проверяет access или refresh token с учетом черного списка и отзыва сессии.
+// @Description  Доступно только конфиденциальным клиентам. Для недействительного токена возвращается {"active": false}.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token                  formData  string  true   "Проверяемый токен"
+// @Param        token_type_hint        formData  string  false  "access_token или refresh_token"
+// @Param        client_id              formData  string  false  "ID клиента, если не передан через HTTP Basic"
+// @Param        client_secret          formData  string  false  "Секрет клиента"
+// @Param        client_assertion_type  formData  string  false  "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param        client_assertion       formData  string  false  "JWT, подписанный ключом клиента"
+// @Success      200  {object}  service.TokenInfo
+// @Failure      400  {object}  handler.OAuthErrorResponse
+// @Failure      401  {object}  handler.OAuthErrorResponse
+// @Router       /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, errors.NewError(errors.ErrorTypeValidation, "Invalid request body", err))
+		return
+	}
+
+	credentials := clientCredentials(r)
+	info, err := h.OAuthService.Introspect(r.Context(), credentials, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		slog.Error("Failed to introspect token", "client_id", credentials.ClientID, "error", err)
+		writeOAuthError(w, err)
+		return
+	}
+
+	writeOAuthJSON(w, http.StatusOK, info)
+}
+
+// Revoke godoc
+// @Summary      Отзыв токена (RFC 7009)
+// @Description  Принимает access или refresh token. Отзыв refresh token завершает сессию и блокирует ее access token,
+// @Description  отзыв access token добавляет сессию в черный список. Неизвестные и просроченные токены игнорируются.
+// @Description  Без учетных данных клиента отзываются только токены собственных сессий сервиса.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token                  formData  string  true   "Отзываемый токен"
+// @Param        token_type_hint        formData  string  false  "access_token или refresh_token"
+// @Param        client_id              formData  string  false  "ID клиента, если не передан через HTTP Basic"
+// @Param        client_secret          formData  string  false  "Секрет клиента"
+// @Param        client_assertion_type  formData  string  false  "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param        client_assertion       formData  string  false  "JWT, подписанный ключом клиента"
+// @Success      200
+// @Failure      400  {object}  handler.OAuthErrorResponse
+// @Failure      401  {object}  handler.OAuthErrorResponse
+// @Router       /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, errors.NewError(errors.ErrorTypeValidation, "Invalid request body", err))
+		return
+	}
+
+	credentials := clientCredentials(r)
+	err := h.OAuthService.Revoke(r.Context(), credentials, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		slog.Error("Failed to revoke token", "client_id", credentials.ClientID, "error", err)
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Logout godoc
+// @Summary      Выход по инициативе клиента (OIDC RP-Initiated Logout)
+// @Description  Отзывает refresh-сессию, для которой выдан ID token из id_token_hint, и блокирует ее access token.
+// @Description  Если передан post_logout_redirect_uri, перенаправляет на него со state.
+// @Tags         oauth
+// @Produce      json
+// @Param        id_token_hint             query     string  true   "ID token, выданный клиенту"
+// @Param        client_id                 query     string  false  "ID клиента"
+// @Param        post_logout_redirect_uri  query     string  false  "Зарегистрированный URI для перенаправления после выхода"
+// @Param        state                     query     string  false  "Состояние клиента"
+// @Success      200  {object}  handler.SuccessResponse
+// @Success      302
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Router       /oauth/logout [get]
+func (h *OAuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid request")
+		return
+	}
+
+	postLogoutRedirectURI := r.Form.Get("post_logout_redirect_uri")
+	err := h.OAuthService.Logout(r.Context(), r.Form.Get("id_token_hint"), r.Form.Get("client_id"), postLogoutRedirectURI)
+	if err != nil {
+		slog.Error("Failed to end client session", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	if postLogoutRedirectURI == "" {
+		WriteSuccess(w, map[string]interface{}{
+			"message": "Session revoked successfully",
+		})
+		return
+	}
+
+	u, err := url.Parse(postLogoutRedirectURI)
+	if err != nil {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid post_logout_redirect_uri")
+		return
+	}
+	if state := r.Form.Get("state"); state != "" {
+		query := u.Query()
+		query.Set("state", state)
+		u.RawQuery = query.Encode()
+	}
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// RegisterClient godoc
+// @Summary      Регистрация OAuth клиента
+// @Description  Создает клиента для authorization code flow. Секрет конфиденциального клиента возвращается только один раз.
+// @Description  Без scopes клиенту доступны openid и email. Без audiences токены клиента действуют только для API этого сервиса.
+// @Description  Требует разрешение clients:manage.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                         true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        client         body      handler.RegisterClientRequest  true  "Параметры клиента"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/oauth/clients [post]
+func (h *OAuthHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+
+	var request RegisterClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Error("Invalid client registration request", "error", err)
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid request body")
+		return
+	}
+
+	client, err := h.OAuthService.RegisterClient(r.Context(), request.Name, request.RedirectURIs, request.PostLogoutRedirectURIs, service.ClientGrant{
+		Scopes:    request.Scopes,
+		Audiences: request.Audiences,
+	}, request.Confidential)
+	if err != nil {
+		slog.Error("Failed to register OAuth client", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, client)
+}
+
+// CreateServiceAccount godoc
+// @Summary      Создать сервисный аккаунт
+// @Description  Создает клиента для grant_type client_credentials. Без public_key выдается секрет, он возвращается только один раз.
+// @Description  С public_key (PEM) клиент аутентифицируется через private_key_jwt.
+// @Description  scopes ограничивают scope токенов, audiences задают aud токенов. Требует разрешение clients:manage.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                         true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        account        body      handler.ServiceAccountRequest  true  "Параметры сервисного аккаунта"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/service-accounts [post]
+func (h *OAuthHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+
+	var request ServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		slog.Error("Invalid service account request", "error", err)
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid request body")
+		return
+	}
+
+	client, err := h.OAuthService.CreateServiceAccount(r.Context(), request.Name, request.PublicKey, service.ClientGrant{
+		Scopes:    request.Scopes,
+		Audiences: request.Audiences,
+	})
+	if err != nil {
+		slog.Error("Failed to create service account", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, client)
+}
+
+// RotateClientSecret godoc
+// @Summary      Ротация секрета клиента
+// @Description  Выдает новый секрет конфиденциальному клиенту, прежний секрет сразу перестает действовать.
+// @Description  Требует разрешение clients:manage.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        client_id      path      string  true  "ID клиента"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /admin/oauth/clients/{client_id}/secret [post]
+func (h *OAuthHandler) RotateClientSecret(w http.ResponseWriter, r *http.Request) {
+
+	clientID := chi.URLParam(r, "client_id")
+
+	secret, err := h.OAuthService.RotateClientSecret(r.Context(), clientID)
+	if err != nil {
+		slog.Error("Failed to rotate client secret", "client_id", clientID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"client_id":     clientID,
+		"client_secret": secret,
+	})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type RBACHandler struct {
+	RBACService *service.RBACService
+}
+
+func NewRBACHandler(rbacService *service.RBACService) *RBACHandler {
+	return &RBACHandler{
+		RBACService: rbacService,
+	}
+}
+
+// ListRoles godoc
+// @Summary      Список ролей
+// @Description  Возвращает роли с их разрешениями. Требует разрешение roles:manage.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/roles [get]
+func (h *RBACHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+
+	roles, err := h.RBACService.ListRoles(r.Context())
+	if err != nil {
+		slog.Error("Failed to list roles", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"roles": roles,
+	})
+}
+
+// GetUserRoles godoc
+// @Summary      Роли пользователя
+// @Description  Требует разрешение roles:manage.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        user_id        path      string  true  "ID пользователя"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Router       /admin/users/{user_id}/roles [get]
+func (h *RBACHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid user ID")
+		return
+	}
+
+	roles, err := h.RBACService.GetUserRoles(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user roles", "user_id", userID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"user_id": userID,
+		"roles":   roles,
+	})
+}
+
+// AssignRole godoc
+// @Summary      Назначить роль пользователю
+// @Description  Роль попадает в access token при следующем входе или обновлении сессии. Требует разрешение roles:manage.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        user_id        path      string  true  "ID пользователя"
+// @Param        role           path      string  true  "Роль"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /admin/users/{user_id}/roles/{role} [put]
+func (h *RBACHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid user ID")
+		return
+	}
+
+	role := chi.URLParam(r, "role")
+	if err := h.RBACService.AssignRole(r.Context(), userID, role); err != nil {
+		slog.Error("Failed to assign role", "user_id", userID, "role", role, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Role assigned successfully",
+	})
+}
+
+// RemoveRole godoc
+// @Summary      Снять роль с пользователя
+// @Description  Требует разрешение roles:manage.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer access_token"  default(Bearer <access_token>)
+// @Param        user_id        path      string  true  "ID пользователя"
+// @Param        role           path      string  true  "Роль"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Failure      403  {object}  handler.Response
+// @Failure      404  {object}  handler.Response
+// @Router       /admin/users/{user_id}/roles/{role} [delete]
+func (h *RBACHandler) RemoveRole(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		WriteTypeError(w, errors.ErrorTypeValidation, "Invalid user ID")
+		return
+	}
+
+	role := chi.URLParam(r, "role")
+	if err := h.RBACService.RemoveRole(r.Context(), userID, role); err != nil {
+		slog.Error("Failed to remove role", "user_id", userID, "role", role, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Role removed successfully",
+	})
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"authservice/internal/config"
+	"authservice/internal/errors"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+)
+
+type WebAuthnHandler struct {
+	AuthService     *service.AuthService
+	WebAuthnService *service.WebAuthnService
+	Tokens          *utils.TokenIssuer
+	Cookie          config.CookieConfig
+	TrustedProxies  utils.TrustedProxies
+}
+
+func NewWebAuthnHandler(authService *service.AuthService, webAuthnService *service.WebAuthnService, tokens *utils.TokenIssuer, cookie config.CookieConfig, trustedProxies utils.TrustedProxies) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		AuthService:     authService,
+		WebAuthnService: webAuthnService,
+		Tokens:          tokens,
+		Cookie:          cookie,
+		TrustedProxies:  trustedProxies,
+	}
+}
+
+func ceremonyID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.URL.Query().Get("ceremony_id")
+	if id == "" {
+		WriteTypeError(w, errors.ErrorTypeValidation, "ceremony_id query parameter required")
+		return "", false
+	}
+	return id, true
+}
+
+// BeginRegistration godoc
+// @Summary      Начать регистрацию passkey
+// @Description  Возвращает ceremony_id и параметры для navigator.credentials.create().
+// @Tags         webauthn
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      401  {object}  handler.Response
+// @Router       /webauthn/register/begin [post]
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		slog.Error("Failed to authenticate passkey registration", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	ceremony, err := h.WebAuthnService.BeginRegistration(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to begin passkey registration", "user_id", userID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, ceremony)
+}
+
+// FinishRegistration godoc
+// @Summary      Завершить регистрацию passkey
+// @Description  Принимает ответ navigator.credentials.create() в теле запроса.
+// @Tags         webauthn
+// @Accept       json
+// @Produce      json
+// @Param        Authorization      header    string  true   "Bearer access_token"  default(Bearer <access_token>)
+// @Param        ceremony_id        query     string  true   "ID церемонии из begin"
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Router       /webauthn/register/finish [post]
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		slog.Error("Failed to authenticate passkey registration", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	id, ok := ceremonyID(w, r)
+	if !ok {
+		return
+	}
+
+	credentialID, err := h.WebAuthnService.FinishRegistration(r.Context(), userID, id, r.Body)
+	if err != nil {
+		slog.Error("Failed to finish passkey registration", "user_id", userID, "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"message":       "Passkey registered successfully",
+		"credential_id": base64.RawURLEncoding.EncodeToString(credentialID),
+	})
+}
+
+// BeginLogin godoc
+// @Summary      Начать вход по passkey
+// @Description  Возвращает ceremony_id и параметры для navigator.credentials.get().
+// @Tags         webauthn
+// @Produce      json
+// @Success      200  {object}  handler.SuccessResponse
+// @Router       /webauthn/login/begin [post]
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+
+	ceremony, err := h.WebAuthnService.BeginLogin(r.Context())
+	if err != nil {
+		slog.Error("Failed to begin passkey login", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, ceremony)
+}
+
+// FinishLogin godoc
+// @Summary      Завершить вход по passkey
+// @Description  Принимает ответ navigator.credentials.get() в теле запроса.
+// @Description  Возвращает access token в заголовке и refresh token в cookie.
+// @Tags         webauthn
+// @Accept       json
+// @Produce      json
+// @Param        ceremony_id       query     string  true   "ID церемонии из begin"
+// @Param        User-Agent        header    string  false  "User-Agent"        default(Swagger-Test)
+// @Param        X-Forwarded-For   header    string  false  "IP адрес клиента"  default(127.0.0.1)
+// @Success      200  {object}  handler.SuccessResponse
+// @Failure      400  {object}  handler.Response
+// @Failure      401  {object}  handler.Response
+// @Header       200  {string}  Access-Token  "Bearer <access_token>"
+// @Set-Cookie   refresh_token=...; Path=/refresh; HttpOnly; Secure; SameSite=Strict
+// @Router       /webauthn/login/finish [post]
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := ceremonyID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, amr, err := h.WebAuthnService.FinishLogin(r.Context(), id, r.Body)
+	if err != nil {
+		slog.Error("Failed to finish passkey login", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	accessToken, refreshToken, err := h.AuthService.NewSession(sessionContext(r, h.TrustedProxies), userID, amr)
+	if err != nil {
+		slog.Error("Failed to create new session", "error", err)
+		WriteError(w, err)
+		return
+	}
+
+	writeSessionTokens(w, h.Cookie, h.Tokens, accessToken, refreshToken)
+
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Session created successfully",
+	})
+}
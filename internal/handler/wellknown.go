@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+type WellKnownHandler struct {
+	Tokens *utils.TokenIssuer
+}
+
+func NewWellKnownHandler(tokens *utils.TokenIssuer) *WellKnownHandler {
+	return &WellKnownHandler{
+		Tokens: tokens,
+	}
+}
+
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgs      []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// JWKS godoc
+// @Summary      Публичные ключи для проверки access token
+// @Description  Возвращает JSON Web Key Set (RFC 7517). Симметричные ключи не публикуются.
+// @Tags         keys
+// @Produce      json
+// @Success      200  {object}  utils.JWKS
+// @Router       /.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(h.Tokens.Keyring.PublicJWKS()); err != nil {
+		slog.Error("Failed to encode JWKS", "error", err)
+	}
+}
+
+// OpenIDConfiguration godoc
+// @Summary      OpenID Connect discovery
+// @Description  Возвращает метаданные провайдера OpenID Connect.
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {object}  handler.OpenIDConfiguration
+// @Router       /.well-known/openid-configuration [get]
+func (h *WellKnownHandler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+
+	issuer := h.Tokens.Issuer
+	configuration := OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		EndSessionEndpoint:                issuer + "/oauth/logout",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		ScopesSupported:                   []string{service.ScopeOpenID, service.ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{service.GrantAuthorizationCode, service.GrantRefreshToken, service.GrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.Tokens.Keyring.SigningAlgorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post", "private_key_jwt"},
+		TokenEndpointAuthSigningAlgs:      []string{"RS256", "ES256", "ES384", "ES512", "EdDSA"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "sid", "amr", "email"},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(configuration); err != nil {
+		slog.Error("Failed to encode OpenID configuration", "error", err)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"authservice/internal/authctx"
+	"authservice/internal/errors"
+	"authservice/internal/handler"
+	"authservice/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+func Authorize(rbac *service.RBACService, permission string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			claims, ok := authctx.FromContext(r.Context())
+			if !ok {
+				slog.Error("Token claims not found in context")
+				handler.WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
+				return
+			}
+
+			allowed, err := rbac.HasPermission(r.Context(), claims.Roles, permission)
+			if err != nil {
+				slog.Error("Failed to check permission", "permission", permission, "error", err)
+				handler.WriteError(w, err)
+				return
+			}
+			if !allowed {
+				slog.Error("Permission denied", "permission", permission, "roles", claims.Roles)
+				handler.WriteTypeError(w, errors.ErrorTypeForbidden, "Permission denied: "+permission+" required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
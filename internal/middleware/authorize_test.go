@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"authservice/internal/config"
+	"authservice/internal/model"
+	"authservice/internal/service"
+	"authservice/internal/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRoleRepository only resolves permissions, the rest is unused here.
+type fakeRoleRepository struct {
+	rolePermissions map[string][]string
+}
+
+func (r *fakeRoleRepository) ListRoles(ctx context.Context) ([]model.Role, error) {
+	return nil, nil
+}
+
+func (r *fakeRoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (r *fakeRoleRepository) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, r.rolePermissions[role]...)
+	}
+	return permissions, nil
+}
+
+func (r *fakeRoleRepository) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return nil
+}
+
+func (r *fakeRoleRepository) RemoveRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return nil
+}
+
+func TestAuthorize(t *testing.T) {
+
+	cfg := config.Default()
+	cfg.JWT.Audience = "https://auth.example.com"
+	tokens := utils.NewTokenIssuer(cfg, utils.NewKeyring(utils.NewHMACSigningKey("test", []byte("test-secret"))))
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	authService := service.NewAuthService(nil, nil, service.NewBlacklistService(redisClient, cfg.JWT.AccessTokenTTL), tokens, cfg.Webhook)
+
+	rbac := service.NewRBACService(&fakeRoleRepository{rolePermissions: map[string][]string{
+		service.RoleAdmin:   {service.PermissionKeysRotate, service.PermissionSessionsRead},
+		service.RoleSupport: {service.PermissionSessionsRead},
+	}})
+
+	handler := AuthMiddleware(authService)(Authorize(rbac, service.PermissionKeysRotate)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }),
+	))
+
+	tests := []struct {
+		name       string
+		claims     *utils.AccessTokenClaims
+		wantStatus int
+	}{
+		{name: "missing token", wantStatus: http.StatusUnauthorized},
+		{name: "role with the permission", claims: &utils.AccessTokenClaims{Roles: []string{service.RoleAdmin}}, wantStatus: http.StatusNoContent},
+		{name: "one of several roles", claims: &utils.AccessTokenClaims{Roles: []string{service.RoleUser, service.RoleAdmin}}, wantStatus: http.StatusNoContent},
+		{name: "role without the permission", claims: &utils.AccessTokenClaims{Roles: []string{service.RoleSupport}}, wantStatus: http.StatusForbidden},
+		{name: "unknown role", claims: &utils.AccessTokenClaims{Roles: []string{"root"}}, wantStatus: http.StatusForbidden},
+		{name: "no roles", claims: &utils.AccessTokenClaims{}, wantStatus: http.StatusForbidden},
+		{name: "OAuth client token", claims: &utils.AccessTokenClaims{ClientID: "app", Roles: []string{service.RoleAdmin}}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/keys/rotate", nil)
+			if tt.claims != nil {
+				claims := *tt.claims
+				claims.UserID = uuid.NewString()
+				claims.SessionID = uuid.NewString()
+				token, err := tokens.GenerateJWT(claims)
+				if err != nil {
+					t.Fatalf("GenerateJWT: %v", err)
+				}
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
@@ -1,56 +1,37 @@
 package middleware
 
 import (
-	"authservice/internal/errors"
+	"authservice/internal/authctx"
 	"authservice/internal/handler"
 	"authservice/internal/service"
-	"authservice/internal/utils"
 	"log/slog"
 	"net/http"
-	"strings"
 )
 
-func AuthMiddleware(blackList *service.BlacklistService) func(http.Handler) http.Handler {
-
-	return func(next http.Handler) http.Handler {
+func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Handler {
+	return verifyToken(authService.VerifyFirstPartyToken)
+}
 
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// OAuthMiddleware also accepts tokens issued to OAuth clients, so routes
+// behind it must check the token scope.
+func OAuthMiddleware(authService *service.AuthService) func(http.Handler) http.Handler {
+	return verifyToken(authService.VerifyAccessToken)
+}
 
-			header := r.Header.Get("Authorization")
-			accessToken := strings.Split(header, " ")[1]
-			if accessToken == "" {
-				slog.Error("Missing access token header")
-				handler.WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
-				return
-			}
+func verifyToken(verify func(accessToken string) (*authctx.Claims, error)) func(http.Handler) http.Handler {
 
-			claims, err := utils.ParseToken(accessToken)
-			if err != nil {
-				slog.Error("Invalid token in middleware", "error", err)
-				handler.WriteTypeError(w, errors.ErrorTypeAuth, "Invalid access token")
-				return
-			}
+	return func(next http.Handler) http.Handler {
 
-			sid, ok := claims["sid"].(string)
-			if !ok {
-				slog.Error("Invalid session ID in token claims")
-				handler.WriteTypeError(w, errors.ErrorTypeAuth, "Invalid session ID in token")
-				return
-			}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-			isBlacklisted, err := blackList.IsTokenBlacklist(sid)
+			claims, err := verify(authctx.BearerToken(r))
 			if err != nil {
-				slog.Error("Error checking token blacklist", "error", err)
-				handler.WriteTypeError(w, errors.ErrorTypeRedis, "Failed check token blacklist")
-				return
-			}
-			if isBlacklisted {
-				slog.Error("Token is blacklisted", "session_id", sid)
-				handler.WriteTypeError(w, errors.ErrorTypeAuth, "Token is blacklisted")
+				slog.Error("Request rejected by auth middleware", "error", err)
+				handler.WriteError(w, err)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(authctx.WithClaims(r.Context(), claims)))
 		})
 	}
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"authservice/internal/authctx"
+	"authservice/internal/errors"
+	"authservice/internal/handler"
+	"log/slog"
+	"net/http"
+)
+
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			claims, ok := authctx.FromContext(r.Context())
+			if !ok {
+				slog.Error("Token claims not found in context")
+				handler.WriteTypeError(w, errors.ErrorTypeAuth, "Access token required")
+				return
+			}
+
+			for _, required := range scopes {
+				if !claims.HasScope(required) {
+					slog.Error("Insufficient token scope", "required", required, "scopes", claims.Scopes)
+					handler.WriteTypeError(w, errors.ErrorTypeForbidden, "Insufficient scope: "+required+" required")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OAuthClient struct {
+	ClientID               string    `db:"client_id"`
+	Name                   string    `db:"name"`
+	ClientSecretHash       string    `db:"client_secret_hash"`
+	PublicKey              string    `db:"public_key"`
+	RedirectURIs           []string  `db:"redirect_uris"`
+	PostLogoutRedirectURIs []string  `db:"post_logout_redirect_uris"`
+	GrantTypes             []string  `db:"grant_types"`
+	Scopes                 []string  `db:"scopes"`
+	Audiences              []string  `db:"audiences"`
+	CreatedAt              time.Time `db:"created_at"`
+}
+
+type OAuthConsent struct {
+	UserID    uuid.UUID `db:"user_id"`
+	ClientID  string    `db:"client_id"`
+	Scopes    []string  `db:"scopes"`
+	CreatedAt time.Time `db:"created_at"`
+}
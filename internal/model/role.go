@@ -0,0 +1,7 @@
+package model
+
+type Role struct {
+	Name        string   `db:"name" json:"name"`
+	Description string   `db:"description" json:"description"`
+	Permissions []string `db:"permissions" json:"permissions"`
+}
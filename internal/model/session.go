@@ -2,14 +2,24 @@ package model
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type RefreshSession struct {
 	ID               int64     `db:"id"`
 	SessionID        string    `db:"session_id"`
+	UserID           uuid.UUID `db:"user_id"`
+	ClientID         string    `db:"client_id"`
+	FamilyID         string    `db:"family_id"`
 	RefreshTokenHash string    `db:"refresh_token_hash"`
 	UserAgent        string    `db:"user_agent"`
 	IPAddress        string    `db:"ip_address"`
+	AMR              []string  `db:"amr"`
+	Scope            string    `db:"scope"`
+	Audience         []string  `db:"audience"`
 	CreatedAt        time.Time `db:"created_at"`
+	RefreshedAt      time.Time `db:"refreshed_at"`
 	Revoked          bool      `db:"revoked"`
+	Rotated          bool      `db:"rotated"`
 }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// SigningKey is published before ActivateAt and kept until RetireAt, when
+// every token it signed has expired.
+type SigningKey struct {
+	KeyID      string     `db:"kid"`
+	Algorithm  string     `db:"algorithm"`
+	PrivateKey []byte     `db:"private_key"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ActivateAt time.Time  `db:"activate_at"`
+	RetireAt   *time.Time `db:"retire_at"`
+}
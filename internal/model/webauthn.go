@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebAuthnCredential struct {
+	ID              []byte     `db:"id"`
+	UserID          uuid.UUID  `db:"user_id"`
+	PublicKey       []byte     `db:"public_key"`
+	AttestationType string     `db:"attestation_type"`
+	Transports      []string   `db:"transports"`
+	Flags           int16      `db:"flags"`
+	AAGUID          []byte     `db:"aaguid"`
+	SignCount       int64      `db:"sign_count"`
+	CloneWarning    bool       `db:"clone_warning"`
+	CreatedAt       time.Time  `db:"created_at"`
+	LastUsedAt      *time.Time `db:"last_used_at"`
+}
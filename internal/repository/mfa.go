@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	stdErrors "errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IMFARepository interface {
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error)
+	SaveTOTP(ctx context.Context, totp *model.TOTP) error
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+}
+
+type MFARepository struct {
+	DBPool *pgxpool.Pool
+}
+
+func NewMFARepository(dbPool *pgxpool.Pool) *MFARepository {
+	return &MFARepository{
+		DBPool: dbPool,
+	}
+}
+
+func (r *MFARepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error) {
+	query := `SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_totp WHERE user_id = $1`
+	var totp model.TOTP
+	err := r.DBPool.QueryRow(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.ConfirmedAt,
+		&totp.LastUsedStep,
+		&totp.CreatedAt,
+	)
+	if err != nil {
+		if stdErrors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.NewError(errors.ErrorTypeNotFound, "TOTP not enrolled", err)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get TOTP", err)
+	}
+	return &totp, nil
+}
+
+func (r *MFARepository) SaveTOTP(ctx context.Context, totp *model.TOTP) error {
+	query := `INSERT INTO user_totp (user_id, secret, created_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+	WHERE user_totp.confirmed_at IS NULL`
+	tag, err := r.DBPool.Exec(ctx, query, totp.UserID, totp.Secret, totp.CreatedAt)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to save TOTP", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeConflict, "TOTP already enrolled", nil)
+	}
+	return nil
+}
+
+func (r *MFARepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.DBPool.Begin(ctx)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to begin transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2
+	WHERE user_id = $1 AND confirmed_at IS NULL`
+	tag, err := tx.Exec(ctx, query, userID, step)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to confirm TOTP", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "no pending TOTP enrollment", nil)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to delete recovery codes", err)
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		query := `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+		if _, err := tx.Exec(ctx, query, userID, codeHash); err != nil {
+			return errors.NewError(errors.ErrorTypeDatabase, "failed to create recovery code", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to commit transaction", err)
+	}
+	return nil
+}
+
+// UseTOTPStep fails for a step at or before the last accepted one, so codes
+// cannot be replayed.
+func (r *MFARepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $2
+	WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2`
+	tag, err := r.DBPool.Exec(ctx, query, userID, step)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to update TOTP", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeAuth, "TOTP code already used", nil)
+	}
+	return nil
+}
+
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	query := `UPDATE recovery_codes SET used_at = NOW()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	tag, err := r.DBPool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to use recovery code", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeAuth, "invalid recovery code", nil)
+	}
+	return nil
+}
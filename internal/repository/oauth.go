@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	stdErrors "errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const oauthClientColumns = `client_id, name, COALESCE(client_secret_hash, ''), COALESCE(public_key, ''), redirect_uris, post_logout_redirect_uris, grant_types, scopes, audiences, created_at`
+
+type IOAuthClientRepository interface {
+	Create(ctx context.Context, client *model.OAuthClient) error
+	GetByID(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	UpdateSecretHash(ctx context.Context, clientID, secretHash string) error
+	GetConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent *model.OAuthConsent) error
+}
+
+type OAuthClientRepository struct {
+	DBPool *pgxpool.Pool
+}
+
+func NewOAuthClientRepository(dbPool *pgxpool.Pool) *OAuthClientRepository {
+	return &OAuthClientRepository{
+		DBPool: dbPool,
+	}
+}
+
+func scanOAuthClient(row pgx.Row) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := row.Scan(
+		&client.ClientID,
+		&client.Name,
+		&client.ClientSecretHash,
+		&client.PublicKey,
+		&client.RedirectURIs,
+		&client.PostLogoutRedirectURIs,
+		&client.GrantTypes,
+		&client.Scopes,
+		&client.Audiences,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		if stdErrors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.NewError(errors.ErrorTypeNotFound, "OAuth client not found", err)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get OAuth client", err)
+	}
+	return &client, nil
+}
+
+func (r *OAuthClientRepository) Create(ctx context.Context, client *model.OAuthClient) error {
+	query := `INSERT INTO oauth_clients
+	(client_id, name, client_secret_hash, public_key, redirect_uris, post_logout_redirect_uris, grant_types, scopes, audiences, created_at)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10)`
+	_, err := r.DBPool.Exec(
+		ctx,
+		query,
+		client.ClientID,
+		client.Name,
+		client.ClientSecretHash,
+		client.PublicKey,
+		client.RedirectURIs,
+		client.PostLogoutRedirectURIs,
+		client.GrantTypes,
+		client.Scopes,
+		client.Audiences,
+		client.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if stdErrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return errors.NewError(errors.ErrorTypeConflict, "OAuth client already exists", err)
+		}
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to create OAuth client", err)
+	}
+	return nil
+}
+
+func (r *OAuthClientRepository) GetByID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE client_id = $1`
+	return scanOAuthClient(r.DBPool.QueryRow(ctx, query, clientID))
+}
+
+func (r *OAuthClientRepository) UpdateSecretHash(ctx context.Context, clientID, secretHash string) error {
+	query := `UPDATE oauth_clients SET client_secret_hash = $2 WHERE client_id = $1 AND client_secret_hash IS NOT NULL`
+	tag, err := r.DBPool.Exec(ctx, query, clientID, secretHash)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to update client secret", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "no client with a secret found", nil)
+	}
+	return nil
+}
+
+func (r *OAuthClientRepository) GetConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error) {
+	query := `SELECT user_id, client_id, scopes, created_at FROM oauth_consents WHERE user_id = $1 AND client_id = $2`
+	var consent model.OAuthConsent
+	err := r.DBPool.QueryRow(ctx, query, userID, clientID).Scan(&consent.UserID, &consent.ClientID, &consent.Scopes, &consent.CreatedAt)
+	if err != nil {
+		if stdErrors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.NewError(errors.ErrorTypeNotFound, "OAuth consent not found", err)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get OAuth consent", err)
+	}
+	return &consent, nil
+}
+
+func (r *OAuthClientRepository) SaveConsent(ctx context.Context, consent *model.OAuthConsent) error {
+	query := `INSERT INTO oauth_consents (user_id, client_id, scopes, created_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, created_at = EXCLUDED.created_at`
+	_, err := r.DBPool.Exec(ctx, query, consent.UserID, consent.ClientID, consent.Scopes, consent.CreatedAt)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to save OAuth consent", err)
+	}
+	return nil
+}
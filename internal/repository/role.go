@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	stdErrors "errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const foreignKeyViolation = "23503"
+
+type IRoleRepository interface {
+	ListRoles(ctx context.Context) ([]model.Role, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GetPermissions(ctx context.Context, roles []string) ([]string, error)
+	AssignRole(ctx context.Context, userID uuid.UUID, role string) error
+	RemoveRole(ctx context.Context, userID uuid.UUID, role string) error
+}
+
+type RoleRepository struct {
+	DBPool *pgxpool.Pool
+}
+
+func NewRoleRepository(dbPool *pgxpool.Pool) *RoleRepository {
+	return &RoleRepository{
+		DBPool: dbPool,
+	}
+}
+
+func (r *RoleRepository) ListRoles(ctx context.Context) ([]model.Role, error) {
+	query := `SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.role = r.name
+	GROUP BY r.name, r.description
+	ORDER BY r.name`
+	rows, err := r.DBPool.Query(ctx, query)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get roles", err)
+	}
+	defer rows.Close()
+
+	roles := make([]model.Role, 0)
+	for rows.Next() {
+		var role model.Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to scan role", err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get roles", err)
+	}
+	return roles, nil
+}
+
+func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`
+	rows, err := r.DBPool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get user roles", err)
+	}
+	roles, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get user roles", err)
+	}
+	return roles, nil
+}
+
+func (r *RoleRepository) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	query := `SELECT DISTINCT permission FROM role_permissions WHERE role = ANY ($1) ORDER BY permission`
+	rows, err := r.DBPool.Query(ctx, query, roles)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get role permissions", err)
+	}
+	permissions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get role permissions", err)
+	}
+	return permissions, nil
+}
+
+func (r *RoleRepository) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `INSERT INTO user_roles (user_id, role, created_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING`
+	_, err := r.DBPool.Exec(ctx, query, userID, role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if stdErrors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return errors.NewError(errors.ErrorTypeNotFound, "user or role not found", err)
+		}
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to assign role", err)
+	}
+	return nil
+}
+
+func (r *RoleRepository) RemoveRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`
+	tag, err := r.DBPool.Exec(ctx, query, userID, role)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to remove role", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "user does not have this role", nil)
+	}
+	return nil
+}
@@ -4,14 +4,42 @@ import (
 	"authservice/internal/errors"
 	"authservice/internal/model"
 	"context"
+	stdErrors "errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const refreshSessionColumns = `id, session_id, user_id, COALESCE(client_id, ''), family_id, refresh_token_hash, user_agent, ip_address, amr, scope, audience, created_at, refreshed_at, revoked, rotated`
+
 type IRefTokenRepository interface {
 	Create(ctx context.Context, refSession *model.RefreshSession) error
 	GetRefreshSession(ctx context.Context, sessionID string) (*model.RefreshSession, error)
+	FindRefreshSession(ctx context.Context, sessionID string) (*model.RefreshSession, error)
+	FindRefreshSessionByTokenHash(ctx context.Context, refreshTokenHash string) (*model.RefreshSession, error)
 	RevokeRefreshSession(ctx context.Context, sessionID string) error
+	RotateRefreshSession(ctx context.Context, sessionID string) error
+	RevokeFamily(ctx context.Context, familyID string) ([]string, error)
+	RevokeUserSession(ctx context.Context, userID uuid.UUID, sessionID string) error
+	GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]model.RefreshSession, error)
+	CountActiveUserSessions(ctx context.Context, userID uuid.UUID) (int, error)
+	RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) ([]string, error)
+	SearchSessions(ctx context.Context, filter SessionFilter) ([]model.RefreshSession, error)
+}
+
+type SessionFilter struct {
+	UserID         uuid.UUID
+	IPAddress      string
+	UserAgent      string
+	FamilyID       string
+	RefreshedAfter time.Time
+	IncludeRevoked bool
+	Limit          int
+	Offset         int
 }
 
 type RefSessionRepository struct {
@@ -24,18 +52,50 @@ func NewRefTokenRepository(dbPool *pgxpool.Pool) *RefSessionRepository {
 	}
 }
 
+func scanRefreshSession(row pgx.Row) (*model.RefreshSession, error) {
+	var refSession model.RefreshSession
+	err := row.Scan(
+		&refSession.ID,
+		&refSession.SessionID,
+		&refSession.UserID,
+		&refSession.ClientID,
+		&refSession.FamilyID,
+		&refSession.RefreshTokenHash,
+		&refSession.UserAgent,
+		&refSession.IPAddress,
+		&refSession.AMR,
+		&refSession.Scope,
+		&refSession.Audience,
+		&refSession.CreatedAt,
+		&refSession.RefreshedAt,
+		&refSession.Revoked,
+		&refSession.Rotated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &refSession, nil
+}
+
 func (r *RefSessionRepository) Create(ctx context.Context, refSession *model.RefreshSession) error {
 	query := `INSERT INTO refresh_sessions
-	(session_id, refresh_token_hash, user_agent, ip_address, created_at, revoked)
-	VALUES ($1, $2, $3, $4, $5, $6)`
+	(session_id, user_id, client_id, family_id, refresh_token_hash, user_agent, ip_address, amr, scope, audience, created_at, refreshed_at, revoked)
+	VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 	_, err := r.DBPool.Exec(
 		ctx,
 		query,
 		refSession.SessionID,
+		refSession.UserID,
+		refSession.ClientID,
+		refSession.FamilyID,
 		refSession.RefreshTokenHash,
 		refSession.UserAgent,
 		refSession.IPAddress,
+		refSession.AMR,
+		refSession.Scope,
+		refSession.Audience,
 		refSession.CreatedAt,
+		refSession.RefreshedAt,
 		refSession.Revoked,
 	)
 	if err != nil {
@@ -45,21 +105,36 @@ func (r *RefSessionRepository) Create(ctx context.Context, refSession *model.Ref
 }
 
 func (r *RefSessionRepository) GetRefreshSession(ctx context.Context, sessionID string) (*model.RefreshSession, error) {
-	query := `SELECT * FROM refresh_sessions WHERE session_id = $1 AND revoked = false`
-	var refSession model.RefreshSession
-	err := r.DBPool.QueryRow(ctx, query, sessionID).Scan(
-		&refSession.ID,
-		&refSession.SessionID,
-		&refSession.RefreshTokenHash,
-		&refSession.UserAgent,
-		&refSession.IPAddress,
-		&refSession.CreatedAt,
-		&refSession.Revoked,
-	)
+	query := `SELECT ` + refreshSessionColumns + ` FROM refresh_sessions WHERE session_id = $1 AND revoked = false`
+	refSession, err := scanRefreshSession(r.DBPool.QueryRow(ctx, query, sessionID))
 	if err != nil {
 		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get refresh session", err)
 	}
-	return &refSession, nil
+	return refSession, nil
+}
+
+func (r *RefSessionRepository) FindRefreshSession(ctx context.Context, sessionID string) (*model.RefreshSession, error) {
+	query := `SELECT ` + refreshSessionColumns + ` FROM refresh_sessions WHERE session_id = $1`
+	refSession, err := scanRefreshSession(r.DBPool.QueryRow(ctx, query, sessionID))
+	if err != nil {
+		if stdErrors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.NewError(errors.ErrorTypeNotFound, "refresh session not found", err)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get refresh session", err)
+	}
+	return refSession, nil
+}
+
+func (r *RefSessionRepository) FindRefreshSessionByTokenHash(ctx context.Context, refreshTokenHash string) (*model.RefreshSession, error) {
+	query := `SELECT ` + refreshSessionColumns + ` FROM refresh_sessions WHERE refresh_token_hash = $1`
+	refSession, err := scanRefreshSession(r.DBPool.QueryRow(ctx, query, refreshTokenHash))
+	if err != nil {
+		if stdErrors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.NewError(errors.ErrorTypeNotFound, "refresh session not found", err)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get refresh session", err)
+	}
+	return refSession, nil
 }
 
 func (r *RefSessionRepository) RevokeRefreshSession(ctx context.Context, sessionID string) error {
@@ -74,3 +149,153 @@ func (r *RefSessionRepository) RevokeRefreshSession(ctx context.Context, session
 	}
 	return nil
 }
+
+// RotateRefreshSession keeps the session, so a reused refresh token is
+// detected.
+func (r *RefSessionRepository) RotateRefreshSession(ctx context.Context, sessionID string) error {
+	query := `UPDATE refresh_sessions SET revoked = true, rotated = true WHERE session_id = $1 AND revoked = false`
+	tag, err := r.DBPool.Exec(ctx, query, sessionID)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to rotate refresh session", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "no active session found", nil)
+	}
+	return nil
+}
+
+func (r *RefSessionRepository) RevokeFamily(ctx context.Context, familyID string) ([]string, error) {
+	query := `UPDATE refresh_sessions SET revoked = true
+	WHERE family_id = $1 AND revoked = false
+	RETURNING session_id`
+	rows, err := r.DBPool.Query(ctx, query, familyID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to revoke session family", err)
+	}
+	sessionIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to revoke session family", err)
+	}
+	return sessionIDs, nil
+}
+
+func (r *RefSessionRepository) RevokeUserSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	query := `UPDATE refresh_sessions SET revoked = true WHERE user_id = $1 AND session_id = $2 AND revoked = false`
+	tag, err := r.DBPool.Exec(ctx, query, userID, sessionID)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to revoke user session", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "no active session found", nil)
+	}
+	return nil
+}
+
+func (r *RefSessionRepository) GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]model.RefreshSession, error) {
+	query := `SELECT ` + refreshSessionColumns + ` FROM refresh_sessions
+	WHERE user_id = $1 AND revoked = false
+	ORDER BY refreshed_at DESC`
+	rows, err := r.DBPool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get user sessions", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]model.RefreshSession, 0)
+	for rows.Next() {
+		refSession, err := scanRefreshSession(rows)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to scan user session", err)
+		}
+		sessions = append(sessions, *refSession)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get user sessions", err)
+	}
+	return sessions, nil
+}
+
+func (r *RefSessionRepository) CountActiveUserSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM refresh_sessions WHERE user_id = $1 AND revoked = false`
+	var count int
+	if err := r.DBPool.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, errors.NewError(errors.ErrorTypeDatabase, "failed to count user sessions", err)
+	}
+	return count, nil
+}
+
+func (r *RefSessionRepository) RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `UPDATE refresh_sessions SET revoked = true
+	WHERE user_id = $1 AND revoked = false
+	RETURNING session_id`
+	rows, err := r.DBPool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to revoke user sessions", err)
+	}
+	sessionIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to revoke user sessions", err)
+	}
+	return sessionIDs, nil
+}
+
+func (r *RefSessionRepository) SearchSessions(ctx context.Context, filter SessionFilter) ([]model.RefreshSession, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.UserID != uuid.Nil {
+		addCondition("user_id = ?", filter.UserID)
+	}
+	if filter.IPAddress != "" {
+		addCondition("ip_address = ?", filter.IPAddress)
+	}
+	if filter.UserAgent != "" {
+		addCondition("strpos(lower(user_agent), lower(?)) > 0", filter.UserAgent)
+	}
+	if filter.FamilyID != "" {
+		addCondition("family_id = ?", filter.FamilyID)
+	}
+	if !filter.RefreshedAfter.IsZero() {
+		addCondition("refreshed_at > ?", filter.RefreshedAfter)
+	}
+	if !filter.IncludeRevoked {
+		conditions = append(conditions, "revoked = false")
+	}
+
+	query := `SELECT ` + refreshSessionColumns + ` FROM refresh_sessions`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY refreshed_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += ` OFFSET $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := r.DBPool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to search sessions", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]model.RefreshSession, 0)
+	for rows.Next() {
+		refSession, err := scanRefreshSession(rows)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to scan session", err)
+		}
+		sessions = append(sessions, *refSession)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to search sessions", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ISigningKeyRepository interface {
+	ListSigningKeys(ctx context.Context) ([]model.SigningKey, error)
+	CreateSigningKey(ctx context.Context, key *model.SigningKey, retirePrevious, createdAfter time.Time) (bool, error)
+	DeleteRetiredSigningKeys(ctx context.Context) error
+}
+
+type SigningKeyRepository struct {
+	DBPool *pgxpool.Pool
+}
+
+func NewSigningKeyRepository(dbPool *pgxpool.Pool) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		DBPool: dbPool,
+	}
+}
+
+func (r *SigningKeyRepository) ListSigningKeys(ctx context.Context) ([]model.SigningKey, error) {
+	query := `SELECT kid, algorithm, private_key, created_at, activate_at, retire_at
+	FROM signing_keys
+	WHERE retire_at IS NULL OR retire_at > NOW()
+	ORDER BY activate_at DESC`
+	rows, err := r.DBPool.Query(ctx, query)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get signing keys", err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.SigningKey])
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get signing keys", err)
+	}
+	return keys, nil
+}
+
+// CreateSigningKey stores nothing and returns false when another replica
+// created a key after createdAfter.
+func (r *SigningKeyRepository) CreateSigningKey(ctx context.Context, key *model.SigningKey, retirePrevious, createdAfter time.Time) (bool, error) {
+	tx, err := r.DBPool.Begin(ctx)
+	if err != nil {
+		return false, errors.NewError(errors.ErrorTypeDatabase, "failed to begin transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serializes rotations of all replicas.
+	if _, err := tx.Exec(ctx, `LOCK TABLE signing_keys IN EXCLUSIVE MODE`); err != nil {
+		return false, errors.NewError(errors.ErrorTypeDatabase, "failed to lock signing keys", err)
+	}
+
+	if !createdAfter.IsZero() {
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM signing_keys WHERE created_at > $1)`
+		if err := tx.QueryRow(ctx, query, createdAfter).Scan(&exists); err != nil {
+			return false, errors.NewError(errors.ErrorTypeDatabase, "failed to check signing keys", err)
+		}
+		if exists {
+			return false, nil
+		}
+	}
+
+	query := `UPDATE signing_keys SET retire_at = $1 WHERE retire_at IS NULL`
+	if _, err := tx.Exec(ctx, query, retirePrevious); err != nil {
+		return false, errors.NewError(errors.ErrorTypeDatabase, "failed to retire signing keys", err)
+	}
+
+	query = `INSERT INTO signing_keys (kid, algorithm, private_key, created_at, activate_at)
+	VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(ctx, query, key.KeyID, key.Algorithm, key.PrivateKey, key.CreatedAt, key.ActivateAt); err != nil {
+		return false, errors.NewError(errors.ErrorTypeDatabase, "failed to create signing key", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, errors.NewError(errors.ErrorTypeDatabase, "failed to commit transaction", err)
+	}
+	return true, nil
+}
+
+func (r *SigningKeyRepository) DeleteRetiredSigningKeys(ctx context.Context) error {
+	query := `DELETE FROM signing_keys WHERE retire_at <= NOW()`
+	if _, err := r.DBPool.Exec(ctx, query); err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to delete retired signing keys", err)
+	}
+	return nil
+}
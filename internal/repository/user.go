@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	stdErrors "errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userColumns = `id, email, password_hash, created_at, updated_at`
+
+const uniqueViolation = "23505"
+
+type IUserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, userID uuid.UUID) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
+}
+
+type UserRepository struct {
+	DBPool *pgxpool.Pool
+}
+
+func NewUserRepository(dbPool *pgxpool.Pool) *UserRepository {
+	return &UserRepository{
+		DBPool: dbPool,
+	}
+}
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if stdErrors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.NewError(errors.ErrorTypeNotFound, "user not found", err)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get user", err)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	// New users get the user role in the same statement.
+	query := `WITH created AS (
+		INSERT INTO users (id, email, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	)
+	INSERT INTO user_roles (user_id, role, created_at) SELECT id, 'user', $4 FROM created`
+	_, err := r.DBPool.Exec(
+		ctx,
+		query,
+		user.ID,
+		user.Email,
+		user.PasswordHash,
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if stdErrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return errors.NewError(errors.ErrorTypeConflict, "user with this email already exists", err)
+		}
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to create user", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(r.DBPool.QueryRow(ctx, query, userID))
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	return scanUser(r.DBPool.QueryRow(ctx, query, email))
+}
+
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`
+	tag, err := r.DBPool.Exec(ctx, query, userID, passwordHash)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to update password hash", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "user not found", nil)
+	}
+	return nil
+}
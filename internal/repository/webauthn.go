@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	stdErrors "errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const webAuthnCredentialColumns = `id, user_id, public_key, attestation_type, transports, flags, aaguid, sign_count, clone_warning, created_at, last_used_at`
+
+type IWebAuthnRepository interface {
+	Create(ctx context.Context, credential *model.WebAuthnCredential) error
+	GetUserCredentials(ctx context.Context, userID uuid.UUID) ([]model.WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, credentialID []byte, signCount int64, cloneWarning bool) error
+}
+
+type WebAuthnRepository struct {
+	DBPool *pgxpool.Pool
+}
+
+func NewWebAuthnRepository(dbPool *pgxpool.Pool) *WebAuthnRepository {
+	return &WebAuthnRepository{
+		DBPool: dbPool,
+	}
+}
+
+func (r *WebAuthnRepository) Create(ctx context.Context, credential *model.WebAuthnCredential) error {
+	query := `INSERT INTO webauthn_credentials
+	(id, user_id, public_key, attestation_type, transports, flags, aaguid, sign_count, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.DBPool.Exec(
+		ctx,
+		query,
+		credential.ID,
+		credential.UserID,
+		credential.PublicKey,
+		credential.AttestationType,
+		credential.Transports,
+		credential.Flags,
+		credential.AAGUID,
+		credential.SignCount,
+		credential.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if stdErrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return errors.NewError(errors.ErrorTypeConflict, "credential already registered", err)
+		}
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to create WebAuthn credential", err)
+	}
+	return nil
+}
+
+func (r *WebAuthnRepository) GetUserCredentials(ctx context.Context, userID uuid.UUID) ([]model.WebAuthnCredential, error) {
+	query := `SELECT ` + webAuthnCredentialColumns + ` FROM webauthn_credentials WHERE user_id = $1`
+	rows, err := r.DBPool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get WebAuthn credentials", err)
+	}
+	credentials, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.WebAuthnCredential, error) {
+		var credential model.WebAuthnCredential
+		err := row.Scan(
+			&credential.ID,
+			&credential.UserID,
+			&credential.PublicKey,
+			&credential.AttestationType,
+			&credential.Transports,
+			&credential.Flags,
+			&credential.AAGUID,
+			&credential.SignCount,
+			&credential.CloneWarning,
+			&credential.CreatedAt,
+			&credential.LastUsedAt,
+		)
+		return credential, err
+	})
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed to get WebAuthn credentials", err)
+	}
+	return credentials, nil
+}
+
+func (r *WebAuthnRepository) UpdateSignCount(ctx context.Context, credentialID []byte, signCount int64, cloneWarning bool) error {
+	query := `UPDATE webauthn_credentials
+	SET sign_count = $2, clone_warning = clone_warning OR $3, last_used_at = NOW()
+	WHERE id = $1`
+	tag, err := r.DBPool.Exec(ctx, query, credentialID, signCount, cloneWarning)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed to update WebAuthn credential", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "WebAuthn credential not found", nil)
+	}
+	return nil
+}
@@ -12,20 +12,72 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func NewRouter(authHandler *handler.AuthHandler, blackList *service.BlacklistService) *chi.Mux {
+func NewRouter(authHandler *handler.AuthHandler, mfaHandler *handler.MFAHandler, webAuthnHandler *handler.WebAuthnHandler, oauthHandler *handler.OAuthHandler, wellKnownHandler *handler.WellKnownHandler, keyHandler *handler.KeyHandler, rbacHandler *handler.RBACHandler, adminSessionHandler *handler.AdminSessionHandler, forwardAuthHandler *handler.ForwardAuthHandler, authService *service.AuthService, rbac *service.RBACService) *chi.Mux {
 	router := chi.NewRouter()
 
 	router.Use(chiMiddleware.Logger)
 
 	router.Get("/docs/*", httpSwagger.WrapHandler)
 
-	router.Get("/new_session/{user_id}", authHandler.NewSession)
+	router.Get("/.well-known/jwks.json", wellKnownHandler.JWKS)
+
+	router.Post("/register", authHandler.Register)
+	router.Post("/login", authHandler.Login)
+	router.Post("/login/mfa", authHandler.LoginMFA)
+	router.Post("/webauthn/login/begin", webAuthnHandler.BeginLogin)
+	router.Post("/webauthn/login/finish", webAuthnHandler.FinishLogin)
 	router.Get("/refresh", authHandler.RefreshSession)
 	router.Post("/refresh/revoke", authHandler.RevokeSession)
+	router.HandleFunc("/forward-auth", forwardAuthHandler.ForwardAuth)
+
+	if oauthHandler != nil {
+		router.Get("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+		router.Post("/oauth/token", oauthHandler.Token)
+		router.Post("/oauth/introspect", oauthHandler.Introspect)
+		router.Post("/oauth/revoke", oauthHandler.Revoke)
+		router.Get("/oauth/logout", oauthHandler.Logout)
+		router.Post("/oauth/logout", oauthHandler.Logout)
+		router.Get("/oauth/authorize", oauthHandler.Authorize)
+
+		router.Group(func(r chi.Router) {
+			r.Use(middleware.OAuthMiddleware(authService))
+			r.Use(middleware.RequireScope(service.ScopeOpenID))
+			r.Get("/userinfo", authHandler.UserInfo)
+			r.Post("/userinfo", authHandler.UserInfo)
+		})
+
+		router.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(authService))
+			r.Post("/oauth/consent", oauthHandler.Consent)
+			r.With(middleware.Authorize(rbac, service.PermissionClientsManage)).Post("/admin/oauth/clients", oauthHandler.RegisterClient)
+			r.With(middleware.Authorize(rbac, service.PermissionClientsManage)).Post("/admin/oauth/clients/{client_id}/secret", oauthHandler.RotateClientSecret)
+			r.With(middleware.Authorize(rbac, service.PermissionClientsManage)).Post("/admin/service-accounts", oauthHandler.CreateServiceAccount)
+		})
+	}
 
 	router.Group(func(r chi.Router) {
-		r.Use(middleware.AuthMiddleware(blackList))
+		r.Use(middleware.AuthMiddleware(authService))
 		r.Get("/me", authHandler.GetAuthenticatedUserID)
+		r.Post("/refresh/revoke_all", authHandler.RevokeAllSessions)
+		r.Get("/sessions", authHandler.ListSessions)
+		r.Delete("/sessions/{session_id}", authHandler.RevokeUserSession)
+		r.Post("/mfa/totp/enroll", mfaHandler.EnrollTOTP)
+		r.Post("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+		r.Post("/webauthn/register/begin", webAuthnHandler.BeginRegistration)
+		r.Post("/webauthn/register/finish", webAuthnHandler.FinishRegistration)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(authService))
+		r.With(middleware.Authorize(rbac, service.PermissionKeysRotate)).Post("/admin/keys/rotate", keyHandler.RotateKeys)
+		r.With(middleware.Authorize(rbac, service.PermissionRolesManage)).Get("/admin/roles", rbacHandler.ListRoles)
+		r.With(middleware.Authorize(rbac, service.PermissionRolesManage)).Get("/admin/users/{user_id}/roles", rbacHandler.GetUserRoles)
+		r.With(middleware.Authorize(rbac, service.PermissionRolesManage)).Put("/admin/users/{user_id}/roles/{role}", rbacHandler.AssignRole)
+		r.With(middleware.Authorize(rbac, service.PermissionRolesManage)).Delete("/admin/users/{user_id}/roles/{role}", rbacHandler.RemoveRole)
+		r.With(middleware.Authorize(rbac, service.PermissionSessionsRead)).Get("/admin/sessions", adminSessionHandler.SearchSessions)
+		r.With(middleware.Authorize(rbac, service.PermissionSessionsRead)).Get("/admin/sessions/{session_id}", adminSessionHandler.GetSession)
+		r.With(middleware.Authorize(rbac, service.PermissionSessionsRevoke)).Delete("/admin/sessions/{session_id}", adminSessionHandler.RevokeSession)
+		r.With(middleware.Authorize(rbac, service.PermissionSessionsRevoke)).Delete("/admin/users/{user_id}/sessions", adminSessionHandler.RevokeUserSessions)
 	})
 
 	return router
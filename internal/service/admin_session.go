@@ -0,0 +1,137 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSessionSearchLimit = 50
+	maxSessionSearchLimit     = 200
+)
+
+type AdminSessionInfo struct {
+	SessionID       string    `json:"session_id"`
+	UserID          string    `json:"user_id"`
+	ClientID        string    `json:"client_id,omitempty"`
+	FamilyID        string    `json:"family_id"`
+	UserAgent       string    `json:"user_agent"`
+	IPAddress       string    `json:"ip_address"`
+	AMR             []string  `json:"amr"`
+	Scope           string    `json:"scope"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	Revoked         bool      `json:"revoked"`
+	Rotated         bool      `json:"rotated"`
+}
+
+type SessionSearch struct {
+	UserID         uuid.UUID
+	IPAddress      string
+	UserAgent      string
+	IncludeRevoked bool
+	Limit          int
+	Offset         int
+}
+
+type AdminSessionService struct {
+	TokenRepo repository.IRefTokenRepository
+	Blacklist *BlacklistService
+}
+
+func NewAdminSessionService(repo repository.IRefTokenRepository, blacklist *BlacklistService) *AdminSessionService {
+	return &AdminSessionService{
+		TokenRepo: repo,
+		Blacklist: blacklist,
+	}
+}
+
+func newAdminSessionInfo(refSession *model.RefreshSession) AdminSessionInfo {
+	return AdminSessionInfo{
+		SessionID:       refSession.SessionID,
+		UserID:          refSession.UserID.String(),
+		ClientID:        refSession.ClientID,
+		FamilyID:        refSession.FamilyID,
+		UserAgent:       refSession.UserAgent,
+		IPAddress:       refSession.IPAddress,
+		AMR:             refSession.AMR,
+		Scope:           refSession.Scope,
+		CreatedAt:       refSession.CreatedAt,
+		LastRefreshedAt: refSession.RefreshedAt,
+		Revoked:         refSession.Revoked,
+		Rotated:         refSession.Rotated,
+	}
+}
+
+func (s *AdminSessionService) SearchSessions(ctx context.Context, search SessionSearch) ([]AdminSessionInfo, error) {
+
+	if search.Limit < 0 || search.Limit > maxSessionSearchLimit || search.Offset < 0 {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "limit must be between 1 and 200 and offset must not be negative", nil)
+	}
+	if search.Limit == 0 {
+		search.Limit = defaultSessionSearchLimit
+	}
+
+	refSessions, err := s.TokenRepo.SearchSessions(ctx, repository.SessionFilter{
+		UserID:         search.UserID,
+		IPAddress:      search.IPAddress,
+		UserAgent:      search.UserAgent,
+		IncludeRevoked: search.IncludeRevoked,
+		Limit:          search.Limit,
+		Offset:         search.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]AdminSessionInfo, 0, len(refSessions))
+	for i := range refSessions {
+		sessions = append(sessions, newAdminSessionInfo(&refSessions[i]))
+	}
+
+	return sessions, nil
+}
+
+func (s *AdminSessionService) GetSession(ctx context.Context, sessionID string) (*AdminSessionInfo, error) {
+
+	refSession, err := s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newAdminSessionInfo(refSession)
+	return &info, nil
+}
+
+func (s *AdminSessionService) RevokeSession(ctx context.Context, sessionID string) error {
+
+	refSession, err := s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.TokenRepo.RevokeFamily(ctx, refSession.FamilyID); err != nil {
+		return err
+	}
+
+	return blacklistLiveSessions(ctx, s.TokenRepo, s.Blacklist, repository.SessionFilter{FamilyID: refSession.FamilyID})
+}
+
+func (s *AdminSessionService) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+
+	sessionIDs, err := s.TokenRepo.RevokeAllUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := blacklistLiveSessions(ctx, s.TokenRepo, s.Blacklist, repository.SessionFilter{UserID: userID}); err != nil {
+		return 0, err
+	}
+
+	return len(sessionIDs), nil
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"authservice/internal/authctx"
+	"authservice/internal/config"
 	"authservice/internal/ctxkeys"
 	"authservice/internal/errors"
 	"authservice/internal/model"
@@ -9,11 +11,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"log/slog"
 	"net/http"
-	"os"
+	"slices"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -23,15 +27,33 @@ type WebHookData struct {
 	SesseionID string `json:"session_id"`
 }
 
+const EventRefreshTokenReuse = "refresh_token_reuse"
+
+type SecurityEvent struct {
+	Event      string    `json:"event"`
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id"`
+	FamilyID   string    `json:"family_id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
 type AuthService struct {
 	TokenRepo repository.IRefTokenRepository
+	RoleRepo  repository.IRoleRepository
 	Blacklist *BlacklistService
+	Tokens    *utils.TokenIssuer
+	Webhook   config.WebhookConfig
 }
 
-func NewAuthService(repo repository.IRefTokenRepository, blacklist *BlacklistService) *AuthService {
+func NewAuthService(repo repository.IRefTokenRepository, roleRepo repository.IRoleRepository, blacklist *BlacklistService, tokens *utils.TokenIssuer, webhook config.WebhookConfig) *AuthService {
 	return &AuthService{
 		TokenRepo: repo,
+		RoleRepo:  roleRepo,
 		Blacklist: blacklist,
+		Tokens:    tokens,
+		Webhook:   webhook,
 	}
 }
 
@@ -46,137 +68,412 @@ func (s *AuthService) NotifyWebHook(oldIP, newIP, sessionID string) error {
 	payload, err := json.Marshal(data)
 	if err != nil {
 		return errors.NewError(errors.ErrorTypeInternal, "failed marshal webhook data", err)
-	} else {
-		webhook := os.Getenv("WEBHOOK_URL")
-		go func() {
-			resp, err := http.Post(webhook, "application/json", bytes.NewBuffer(payload))
-			if err != nil {
-				slog.Error("Failed send webhook notification", "error", err)
-			} else {
-				resp.Body.Close()
-			}
-		}()
 	}
 
+	s.postWebHook(payload)
+	return nil
+}
+
+func (s *AuthService) NotifySecurityEvent(event SecurityEvent) error {
+
+	slog.Warn("Security event", "event", event.Event, "user_id", event.UserID,
+		"session_id", event.SessionID, "family_id", event.FamilyID, "ip_address", event.IPAddress)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeInternal, "failed marshal security event", err)
+	}
+
+	s.postWebHook(payload)
 	return nil
 }
 
-func (s *AuthService) NewSession(ctx context.Context, userID uuid.UUID) (string, string, error) {
+func (s *AuthService) postWebHook(payload []byte) {
+	if s.Webhook.URL == "" {
+		return
+	}
+	client := &http.Client{Timeout: s.Webhook.Timeout}
+	go func() {
+		resp, err := client.Post(s.Webhook.URL, "application/json", bytes.NewBuffer(payload))
+		if err != nil {
+			slog.Error("Failed send webhook notification", "error", err)
+		} else {
+			resp.Body.Close()
+		}
+	}()
+}
+
+type SessionInfo struct {
+	SessionID       string    `json:"session_id"`
+	UserAgent       string    `json:"user_agent"`
+	IPAddress       string    `json:"ip_address"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	Current         bool      `json:"current"`
+}
+
+const FirstPartyScope = "openid email"
+
+type SessionGrant struct {
+	ClientID string
+	Scope    string
+	Audience []string
+	AMR      []string
+}
+
+func (s *AuthService) NewSession(ctx context.Context, userID uuid.UUID, amr []string) (string, string, error) {
+	issued, err := s.createSession(ctx, userID, SessionGrant{Scope: FirstPartyScope, AMR: amr}, nil)
+	if err != nil {
+		return "", "", err
+	}
+	return issued.AccessToken, issued.RefreshToken, nil
+}
+
+type IssuedSession struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	Scope        string
+}
+
+func (s *AuthService) NewClientSession(ctx context.Context, userID uuid.UUID, grant SessionGrant) (*IssuedSession, error) {
+	return s.createSession(ctx, userID, grant, nil)
+}
+
+func (s *AuthService) createSession(ctx context.Context, userID uuid.UUID, grant SessionGrant, parent *model.RefreshSession) (*IssuedSession, error) {
 
 	sessionID := uuid.New().String()
+	familyID := sessionID
+	createdAt := time.Now()
+	if parent != nil {
+		familyID = parent.FamilyID
+		createdAt = parent.CreatedAt
+		grant = SessionGrant{
+			ClientID: parent.ClientID,
+			Scope:    parent.Scope,
+			Audience: parent.Audience,
+			AMR:      parent.AMR,
+		}
+	}
+	if grant.AMR == nil {
+		grant.AMR = []string{}
+	}
+	if grant.Audience == nil {
+		grant.Audience = []string{}
+	}
 	strID := userID.String()
 
-	accessToken, err := utils.GenerateJWT(strID, sessionID)
+	// Roles are resolved on every refresh so role changes reach live
+	// sessions. Tokens issued to OAuth clients never carry them, so a third
+	// party app cannot act with the user's admin permissions.
+	var roles []string
+	if grant.ClientID == "" {
+		var err error
+		roles, err = s.RoleRepo.GetUserRoles(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, err := s.Tokens.GenerateJWT(utils.AccessTokenClaims{
+		UserID:    strID,
+		SessionID: sessionID,
+		ClientID:  grant.ClientID,
+		AMR:       grant.AMR,
+		Scope:     grant.Scope,
+		Audience:  grant.Audience,
+		Roles:     roles,
+		AuthTime:  createdAt,
+	})
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeInternal, "failed generate access token", err)
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed generate access token", err)
 	}
 
-	refreshToken, refreshTokenHash, err := utils.GenerateRefreshToken()
+	refreshToken, refreshTokenHash, err := s.Tokens.GenerateRefreshToken()
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeInternal, "failed generate refresh token", err)
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed generate refresh token", err)
 	}
 
 	ua := ctx.Value(ctxkeys.UserAgentKey).(string)
 	if ua == "" {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "user agent not found in context", nil)
+		return nil, errors.NewError(errors.ErrorTypeAuth, "user agent not found in context", nil)
 	}
 
 	ip := ctx.Value(ctxkeys.IPAddressKey).(string)
 	if ip == "" {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "ip address not found in context", nil)
+		return nil, errors.NewError(errors.ErrorTypeAuth, "ip address not found in context", nil)
 	}
 
 	refSession := &model.RefreshSession{
 		SessionID:        sessionID,
+		UserID:           userID,
+		ClientID:         grant.ClientID,
+		FamilyID:         familyID,
 		RefreshTokenHash: refreshTokenHash,
 		UserAgent:        ua,
 		IPAddress:        ip,
-		CreatedAt:        time.Now(),
+		AMR:              grant.AMR,
+		Scope:            grant.Scope,
+		Audience:         grant.Audience,
+		CreatedAt:        createdAt,
+		RefreshedAt:      time.Now(),
 		Revoked:          false,
 	}
 	if err := s.TokenRepo.Create(ctx, refSession); err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeDatabase, "failed create session in database", err)
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed create session in database", err)
 	}
 
-	return accessToken, refreshToken, nil
+	return &IssuedSession{
+		SessionID:    sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scope:        grant.Scope,
+	}, nil
 }
 
-func (s *AuthService) GetUserID(ctx context.Context, accessToken string) (string, error) {
+func (s *AuthService) VerifyAccessToken(accessToken string) (*authctx.Claims, error) {
+	return s.verifyAccessToken(accessToken, s.Tokens.ParseToken)
+}
 
-	claims, err := utils.ParseToken(accessToken)
+func (s *AuthService) VerifyAccessTokenFor(accessToken, audience string) (*authctx.Claims, error) {
+	return s.verifyAccessToken(accessToken, func(accessToken string) (jwt.MapClaims, error) {
+		return s.Tokens.ParseAccessToken(accessToken, audience)
+	})
+}
+
+func (s *AuthService) verifyAccessToken(accessToken string, parse func(string) (jwt.MapClaims, error)) (*authctx.Claims, error) {
+
+	if accessToken == "" {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "Access token required", nil)
+	}
+
+	mapClaims, err := parse(accessToken)
 	if err != nil {
-		return "", errors.NewError(errors.ErrorTypeAuth, "failed parse access token", err)
+		return nil, errors.NewError(errors.ErrorTypeAuth, "Invalid access token", err)
 	}
 
-	userID, ok := claims["uid"].(string)
-	if !ok {
-		return "", errors.NewError(errors.ErrorTypeAuth, "invalid user ID in token claims", nil)
+	claims, err := authctx.FromMapClaims(mapClaims)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "Invalid access token", err)
 	}
 
-	return userID, nil
+	blacklisted, err := s.Blacklist.IsTokenBlacklist(claims.SessionID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeRedis, "Failed check token blacklist", err)
+	}
+	if blacklisted {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "Token is blacklisted", nil)
+	}
+
+	return claims, nil
 }
 
-func (s *AuthService) RefreshSession(ctx context.Context, Access_token, RefreshToken string) (string, string, error) {
+// VerifyFirstPartyToken rejects tokens issued to OAuth clients, which must be
+// limited to the scopes they were granted.
+func (s *AuthService) VerifyFirstPartyToken(accessToken string) (*authctx.Claims, error) {
 
-	claims, err := utils.ParseToken(Access_token)
+	claims, err := s.VerifyAccessToken(accessToken)
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "failed parse access token", err)
+		return nil, err
 	}
 
-	sessionID, ok := claims["sid"].(string)
-	if !ok {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid session ID in token claims", nil)
+	if claims.ClientID != "" {
+		return nil, errors.NewError(errors.ErrorTypeForbidden, "Access token was issued to an OAuth client", nil)
+	}
+
+	return claims, nil
+}
+
+func (s *AuthService) RefreshSession(ctx context.Context, Access_token, RefreshToken string) (string, string, error) {
+	issued, err := s.refreshSession(ctx, "", Access_token, RefreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	return issued.AccessToken, issued.RefreshToken, nil
+}
+
+func (s *AuthService) RefreshClientSession(ctx context.Context, clientID, refreshToken string) (*IssuedSession, error) {
+	return s.refreshSession(ctx, clientID, "", refreshToken)
+}
+
+func (s *AuthService) refreshSession(ctx context.Context, clientID, Access_token, RefreshToken string) (*IssuedSession, error) {
+
+	// The access token is only a hint: the refresh token is the credential.
+	var claims jwt.MapClaims
+	var sessionID string
+	if Access_token != "" {
+		var err error
+		claims, err = s.Tokens.ParseExpiredToken(Access_token, s.Tokens.RefreshTokenTTL)
+		switch {
+		case stderrors.Is(err, utils.ErrUnknownSigningKey):
+			claims = nil
+		case err != nil:
+			return nil, errors.NewError(errors.ErrorTypeAuth, "failed parse access token", err)
+		default:
+			var ok bool
+			sessionID, ok = claims["sid"].(string)
+			if !ok {
+				return nil, errors.NewError(errors.ErrorTypeAuth, "invalid session ID in token claims", nil)
+			}
+		}
 	}
 
-	refSession, err := s.TokenRepo.GetRefreshSession(ctx, sessionID)
+	refSession, err := s.findRefreshSession(ctx, sessionID, RefreshToken)
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "failed get refresh session", err)
+		return nil, err
+	}
+
+	if refSession.Revoked {
+		if refSession.Rotated {
+			return nil, s.handleRefreshTokenReuse(ctx, refSession)
+		}
+		return nil, errors.NewError(errors.ErrorTypeAuth, "refresh session revoked", nil)
+	}
+
+	// Each refresh issues a new token, so the TTL counts from the last refresh.
+	if time.Since(refSession.RefreshedAt) > s.Tokens.RefreshTokenTTL {
+		if err := s.revokeRefreshSession(ctx, refSession.SessionID); err != nil {
+			return nil, err
+		}
+		return nil, errors.NewError(errors.ErrorTypeAuth, "refresh token expired", nil)
+	}
+
+	if sessionID != "" && refSession.SessionID != sessionID {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "refresh token does not belong to session", nil)
+	}
+
+	if refSession.ClientID != clientID {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "refresh token was issued to another client", nil)
 	}
 
 	ua := ctx.Value(ctxkeys.UserAgentKey).(string)
 	if refSession.UserAgent != ua {
-		err = s.RevokeSession(ctx, Access_token, RefreshToken)
+		err = s.revokeRefreshSession(ctx, refSession.SessionID)
 		if err != nil {
-			return "", "", errors.NewError(errors.ErrorTypeAuth, "failed revoke old session", err)
+			return nil, errors.NewError(errors.ErrorTypeAuth, "failed revoke old session", err)
 		}
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "user agent mismatch", nil)
+		return nil, errors.NewError(errors.ErrorTypeAuth, "user agent mismatch", nil)
 	}
 
 	ip := ctx.Value(ctxkeys.IPAddressKey).(string)
 	if refSession.IPAddress != ip {
-		s.NotifyWebHook(refSession.IPAddress, ip, sessionID)
+		s.NotifyWebHook(refSession.IPAddress, ip, refSession.SessionID)
 	}
 
-	if !utils.CheckRefreshToken(RefreshToken, refSession.RefreshTokenHash) {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid refresh token", nil)
+	err = s.TokenRepo.RotateRefreshSession(ctx, refSession.SessionID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			// Another request rotated the session between the lookup and
+			// the update, so the same refresh token was used twice.
+			return nil, s.handleRefreshTokenReuse(ctx, refSession)
+		}
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed revoke old session", err)
 	}
 
-	err = s.TokenRepo.RevokeRefreshSession(ctx, sessionID)
+	userID := refSession.UserID
+	if userID == uuid.Nil {
+		// Sessions created before user IDs were stored only carry the
+		// user in the access token.
+		userIDStr, ok := claims["uid"].(string)
+		if !ok {
+			return nil, errors.NewError(errors.ErrorTypeAuth, "invalid user ID in token claims", nil)
+		}
+
+		userID, err = uuid.Parse(userIDStr)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeAuth, "invalid user ID format", err)
+		}
+	}
+
+	issued, err := s.createSession(ctx, userID, SessionGrant{}, refSession)
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeDatabase, "failed revoke old session", err)
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed create new session", err)
 	}
 
-	userIDStr, ok := claims["uid"].(string)
-	if !ok {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid user ID in token claims", nil)
+	return issued, nil
+}
+
+func (s *AuthService) revokeRefreshSession(ctx context.Context, sessionID string) error {
+
+	if err := s.TokenRepo.RevokeRefreshSession(ctx, sessionID); err != nil {
+		return errors.NewError(errors.ErrorTypeDatabase, "failed revoke session", err)
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	if err := s.Blacklist.AddToken(sessionID, s.Tokens.AccessTokenTTL); err != nil {
+		return errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+	}
+
+	return nil
+}
+
+// Sessions created before keyed hashes store a bcrypt hash, so they are found
+// by sessionID and checked with bcrypt instead.
+func (s *AuthService) findRefreshSession(ctx context.Context, sessionID, refreshToken string) (*model.RefreshSession, error) {
+
+	tokenHash, err := s.Tokens.HashRefreshToken(refreshToken)
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid user ID format", err)
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed hash refresh token", err)
+	}
+
+	refSession, err := s.TokenRepo.FindRefreshSessionByTokenHash(ctx, tokenHash)
+	if err == nil {
+		return refSession, nil
+	}
+	if appErr, ok := errors.IsAppError(err); !ok || appErr.Type != errors.ErrorTypeNotFound {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed get refresh session", err)
+	}
+
+	if sessionID == "" {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid refresh token", nil)
+	}
+
+	refSession, err = s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "failed get refresh session", err)
+	}
+
+	if !utils.IsLegacyRefreshTokenHash(refSession.RefreshTokenHash) ||
+		!s.Tokens.CheckRefreshToken(refreshToken, refSession.RefreshTokenHash) {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid refresh token", nil)
 	}
 
-	access_token, refreshToken, err := s.NewSession(ctx, userID)
+	return refSession, nil
+}
+
+// handleRefreshTokenReuse revokes the whole token family (OAuth 2.0 Security
+// BCP): either the client or an attacker holds a stolen token and there is no
+// way to tell which.
+func (s *AuthService) handleRefreshTokenReuse(ctx context.Context, refSession *model.RefreshSession) error {
+
+	sessionIDs, err := s.TokenRepo.RevokeFamily(ctx, refSession.FamilyID)
 	if err != nil {
-		return "", "", errors.NewError(errors.ErrorTypeInternal, "failed create new session", err)
+		return errors.NewError(errors.ErrorTypeDatabase, "failed revoke session family", err)
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := s.Blacklist.AddToken(sessionID, s.Tokens.AccessTokenTTL); err != nil {
+			return errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+		}
 	}
 
-	return access_token, refreshToken, nil
+	ua, _ := ctx.Value(ctxkeys.UserAgentKey).(string)
+	ip, _ := ctx.Value(ctxkeys.IPAddressKey).(string)
+	s.NotifySecurityEvent(SecurityEvent{
+		Event:      EventRefreshTokenReuse,
+		UserID:     refSession.UserID.String(),
+		SessionID:  refSession.SessionID,
+		FamilyID:   refSession.FamilyID,
+		IPAddress:  ip,
+		UserAgent:  ua,
+		OccurredAt: time.Now(),
+	})
+
+	return errors.NewError(errors.ErrorTypeAuth, "refresh token reuse detected", nil)
 }
 
 func (s *AuthService) RevokeSession(ctx context.Context, access_token, refreshToken string) error {
 
-	claims, err := utils.ParseToken(access_token)
+	claims, err := s.Tokens.ParseToken(access_token)
 	if err != nil {
 		return errors.NewError(errors.ErrorTypeAuth, "failed parse access token", err)
 	}
@@ -191,7 +488,7 @@ func (s *AuthService) RevokeSession(ctx context.Context, access_token, refreshTo
 		return errors.NewError(errors.ErrorTypeDatabase, "failed revoke session", err)
 	}
 
-	ttlToken, err := utils.GetJWTTTL(access_token)
+	ttlToken, err := s.Tokens.GetJWTTTL(access_token)
 	if err != nil {
 		return errors.NewError(errors.ErrorTypeAuth, "failed get token TTL", err)
 	}
@@ -203,3 +500,303 @@ func (s *AuthService) RevokeSession(ctx context.Context, access_token, refreshTo
 
 	return nil
 }
+
+func (s *AuthService) SessionAuthTime(ctx context.Context, sessionID string) (time.Time, error) {
+
+	refSession, err := s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return refSession.CreatedAt, nil
+}
+
+func (s *AuthService) EndClientSession(ctx context.Context, clientID, sessionID string) error {
+
+	refSession, err := s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if refSession.ClientID != clientID {
+		return errors.NewError(errors.ErrorTypeAuth, "session was issued to another client", nil)
+	}
+	if refSession.Revoked {
+		return nil
+	}
+
+	return s.revokeRefreshSession(ctx, sessionID)
+}
+
+type TokenInfo struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+}
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// Access tokens of sessions replaced by a refresh stay active until they
+// expire. Errors are returned only when the state cannot be checked.
+func (s *AuthService) IntrospectAccessToken(ctx context.Context, accessToken string) (*TokenInfo, error) {
+
+	inactive := &TokenInfo{Active: false}
+
+	// Resource servers introspect tokens issued for their own audience, so
+	// any audience is accepted.
+	claims, err := s.Tokens.ParseAccessToken(accessToken, "")
+	if err != nil {
+		return inactive, nil
+	}
+
+	info := &TokenInfo{
+		Active:    true,
+		TokenType: TokenTypeAccess,
+	}
+	info.Subject, _ = claims["uid"].(string)
+	if info.Subject == "" {
+		info.Subject, _ = claims.GetSubject()
+	}
+	info.ClientID, _ = claims["client_id"].(string)
+	info.Scope, _ = claims["scope"].(string)
+	info.Audience, _ = claims.GetAudience()
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Unix()
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		info.IssuedAt = iat.Unix()
+	}
+
+	// Tokens issued with the client_credentials grant have no session and
+	// are blacklisted by their jti.
+	sessionID, _ := claims["sid"].(string)
+	blacklistKey := sessionID
+	if sessionID == "" {
+		blacklistKey, _ = claims["jti"].(string)
+	}
+
+	blacklisted, err := s.Blacklist.IsTokenBlacklist(blacklistKey)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeRedis, "failed check token blacklist", err)
+	}
+	if blacklisted {
+		return inactive, nil
+	}
+
+	if sessionID == "" {
+		return info, nil
+	}
+	info.SessionID = sessionID
+
+	refSession, err := s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return inactive, nil
+		}
+		return nil, err
+	}
+	if refSession.Revoked && !refSession.Rotated {
+		return inactive, nil
+	}
+	if info.ClientID == "" {
+		info.ClientID = refSession.ClientID
+	}
+
+	return info, nil
+}
+
+func (s *AuthService) IntrospectRefreshToken(ctx context.Context, refreshToken string) (*TokenInfo, error) {
+
+	inactive := &TokenInfo{Active: false}
+
+	tokenHash, err := s.Tokens.HashRefreshToken(refreshToken)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed hash refresh token", err)
+	}
+
+	refSession, err := s.TokenRepo.FindRefreshSessionByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return inactive, nil
+		}
+		return nil, err
+	}
+
+	expiresAt := refSession.RefreshedAt.Add(s.Tokens.RefreshTokenTTL)
+	if refSession.Revoked || time.Now().After(expiresAt) {
+		return inactive, nil
+	}
+
+	return &TokenInfo{
+		Active:    true,
+		TokenType: TokenTypeRefresh,
+		Subject:   refSession.UserID.String(),
+		SessionID: refSession.SessionID,
+		ClientID:  refSession.ClientID,
+		Scope:     refSession.Scope,
+		ExpiresAt: expiresAt.Unix(),
+		IssuedAt:  refSession.RefreshedAt.Unix(),
+	}, nil
+}
+
+// RevokeToken ignores invalid, expired and already revoked tokens, as RFC 7009
+// requires.
+func (s *AuthService) RevokeToken(ctx context.Context, clientID, token, tokenTypeHint string) error {
+
+	revokers := []func(context.Context, string, string) (bool, error){
+		s.revokeAccessToken,
+		s.revokeRefreshToken,
+	}
+	if tokenTypeHint == TokenTypeRefresh {
+		slices.Reverse(revokers)
+	}
+
+	for _, revoke := range revokers {
+		found, err := revoke(ctx, clientID, token)
+		if err != nil || found {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *AuthService) revokeAccessToken(ctx context.Context, clientID, accessToken string) (bool, error) {
+
+	claims, err := s.Tokens.ParseAccessToken(accessToken, "")
+	if err != nil {
+		return false, nil
+	}
+
+	ttl, err := s.Tokens.GetJWTTTL(accessToken)
+	if err != nil {
+		return false, nil
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		tokenClientID, _ := claims["client_id"].(string)
+		if tokenClientID != clientID {
+			return true, errors.NewError(errors.ErrorTypeAuth, "token was issued to another client", nil)
+		}
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			return true, nil
+		}
+		if err := s.Blacklist.AddToken(jti, ttl); err != nil {
+			return true, errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+		}
+		return true, nil
+	}
+
+	refSession, err := s.TokenRepo.FindRefreshSession(ctx, sessionID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return true, nil
+		}
+		return true, err
+	}
+	if refSession.ClientID != clientID {
+		return true, errors.NewError(errors.ErrorTypeAuth, "token was issued to another client", nil)
+	}
+
+	if err := s.Blacklist.AddToken(sessionID, ttl); err != nil {
+		return true, errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+	}
+
+	return true, nil
+}
+
+func (s *AuthService) revokeRefreshToken(ctx context.Context, clientID, refreshToken string) (bool, error) {
+
+	tokenHash, err := s.Tokens.HashRefreshToken(refreshToken)
+	if err != nil {
+		return false, errors.NewError(errors.ErrorTypeInternal, "failed hash refresh token", err)
+	}
+
+	refSession, err := s.TokenRepo.FindRefreshSessionByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if refSession.ClientID != clientID {
+		return true, errors.NewError(errors.ErrorTypeAuth, "token was issued to another client", nil)
+	}
+	if refSession.Revoked {
+		return true, nil
+	}
+
+	if err := s.TokenRepo.RevokeRefreshSession(ctx, refSession.SessionID); err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			// The session was revoked or rotated concurrently.
+			return true, nil
+		}
+		return true, errors.NewError(errors.ErrorTypeDatabase, "failed revoke session", err)
+	}
+
+	if err := s.Blacklist.AddToken(refSession.SessionID, s.Tokens.AccessTokenTTL); err != nil {
+		return true, errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+	}
+
+	return true, nil
+}
+
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+
+	sessionIDs, err := s.TokenRepo.RevokeAllUserSessions(ctx, userID)
+	if err != nil {
+		return 0, errors.NewError(errors.ErrorTypeDatabase, "failed revoke user sessions", err)
+	}
+
+	if err := blacklistLiveSessions(ctx, s.TokenRepo, s.Blacklist, repository.SessionFilter{UserID: userID}); err != nil {
+		return 0, err
+	}
+
+	return len(sessionIDs), nil
+}
+
+func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]SessionInfo, error) {
+
+	refSessions, err := s.TokenRepo.GetActiveUserSessions(ctx, userID)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeDatabase, "failed get user sessions", err)
+	}
+
+	sessions := make([]SessionInfo, 0, len(refSessions))
+	for _, refSession := range refSessions {
+		sessions = append(sessions, SessionInfo{
+			SessionID:       refSession.SessionID,
+			UserAgent:       refSession.UserAgent,
+			IPAddress:       refSession.IPAddress,
+			CreatedAt:       refSession.CreatedAt,
+			LastRefreshedAt: refSession.RefreshedAt,
+			Current:         refSession.SessionID == currentSessionID,
+		})
+	}
+
+	return sessions, nil
+}
+
+func (s *AuthService) RevokeUserSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+
+	if err := s.TokenRepo.RevokeUserSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	if err := s.Blacklist.AddToken(sessionID, s.Tokens.AccessTokenTTL); err != nil {
+		return errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"authservice/internal/config"
+	"authservice/internal/ctxkeys"
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"authservice/internal/utils"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testUserAgent = "test-agent"
+	testIPAddress = "192.0.2.10"
+)
+
+type fakeRefTokenRepository struct {
+	sessions map[string]*model.RefreshSession
+}
+
+func (r *fakeRefTokenRepository) find(match func(*model.RefreshSession) bool) (*model.RefreshSession, error) {
+	for _, refSession := range r.sessions {
+		if match(refSession) {
+			found := *refSession
+			return &found, nil
+		}
+	}
+	return nil, errors.NewError(errors.ErrorTypeNotFound, "refresh session not found", nil)
+}
+
+// revoke revokes the active sessions matching the filter and returns their IDs.
+func (r *fakeRefTokenRepository) revoke(match func(*model.RefreshSession) bool) []string {
+	sessionIDs := []string{}
+	for _, refSession := range r.sessions {
+		if !refSession.Revoked && match(refSession) {
+			refSession.Revoked = true
+			sessionIDs = append(sessionIDs, refSession.SessionID)
+		}
+	}
+	return sessionIDs
+}
+
+func (r *fakeRefTokenRepository) Create(ctx context.Context, refSession *model.RefreshSession) error {
+	stored := *refSession
+	r.sessions[refSession.SessionID] = &stored
+	return nil
+}
+
+func (r *fakeRefTokenRepository) GetRefreshSession(ctx context.Context, sessionID string) (*model.RefreshSession, error) {
+	return r.find(func(s *model.RefreshSession) bool { return s.SessionID == sessionID && !s.Revoked })
+}
+
+func (r *fakeRefTokenRepository) FindRefreshSession(ctx context.Context, sessionID string) (*model.RefreshSession, error) {
+	return r.find(func(s *model.RefreshSession) bool { return s.SessionID == sessionID })
+}
+
+func (r *fakeRefTokenRepository) FindRefreshSessionByTokenHash(ctx context.Context, refreshTokenHash string) (*model.RefreshSession, error) {
+	return r.find(func(s *model.RefreshSession) bool { return s.RefreshTokenHash == refreshTokenHash })
+}
+
+func (r *fakeRefTokenRepository) RevokeRefreshSession(ctx context.Context, sessionID string) error {
+	if len(r.revoke(func(s *model.RefreshSession) bool { return s.SessionID == sessionID })) == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "no active session found", nil)
+	}
+	return nil
+}
+
+func (r *fakeRefTokenRepository) RotateRefreshSession(ctx context.Context, sessionID string) error {
+	refSession, ok := r.sessions[sessionID]
+	if !ok || refSession.Revoked {
+		return errors.NewError(errors.ErrorTypeNotFound, "no active session found", nil)
+	}
+	refSession.Revoked = true
+	refSession.Rotated = true
+	return nil
+}
+
+func (r *fakeRefTokenRepository) RevokeFamily(ctx context.Context, familyID string) ([]string, error) {
+	return r.revoke(func(s *model.RefreshSession) bool { return s.FamilyID == familyID }), nil
+}
+
+func (r *fakeRefTokenRepository) RevokeUserSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	if len(r.revoke(func(s *model.RefreshSession) bool { return s.UserID == userID && s.SessionID == sessionID })) == 0 {
+		return errors.NewError(errors.ErrorTypeNotFound, "no active session found", nil)
+	}
+	return nil
+}
+
+func (r *fakeRefTokenRepository) GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]model.RefreshSession, error) {
+	sessions := []model.RefreshSession{}
+	for _, refSession := range r.sessions {
+		if refSession.UserID == userID && !refSession.Revoked {
+			sessions = append(sessions, *refSession)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeRefTokenRepository) CountActiveUserSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	sessions, err := r.GetActiveUserSessions(ctx, userID)
+	return len(sessions), err
+}
+
+func (r *fakeRefTokenRepository) RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return r.revoke(func(s *model.RefreshSession) bool { return s.UserID == userID }), nil
+}
+
+func (r *fakeRefTokenRepository) SearchSessions(ctx context.Context, filter repository.SessionFilter) ([]model.RefreshSession, error) {
+	sessions := []model.RefreshSession{}
+	for _, refSession := range r.sessions {
+		if (filter.UserID == uuid.Nil || refSession.UserID == filter.UserID) && (filter.IncludeRevoked || !refSession.Revoked) {
+			sessions = append(sessions, *refSession)
+		}
+	}
+	return sessions, nil
+}
+
+type fakeRoleRepository struct {
+	userRoles       map[uuid.UUID][]string
+	rolePermissions map[string][]string
+}
+
+func (r *fakeRoleRepository) ListRoles(ctx context.Context) ([]model.Role, error) {
+	roles := []model.Role{}
+	for name, permissions := range r.rolePermissions {
+		roles = append(roles, model.Role{Name: name, Permissions: permissions})
+	}
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return r.userRoles[userID], nil
+}
+
+func (r *fakeRoleRepository) GetPermissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := []string{}
+	for _, role := range roles {
+		permissions = append(permissions, r.rolePermissions[role]...)
+	}
+	return permissions, nil
+}
+
+func (r *fakeRoleRepository) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	r.userRoles[userID] = append(r.userRoles[userID], role)
+	return nil
+}
+
+func (r *fakeRoleRepository) RemoveRole(ctx context.Context, userID uuid.UUID, role string) error {
+	roles := []string{}
+	for _, assigned := range r.userRoles[userID] {
+		if assigned != role {
+			roles = append(roles, assigned)
+		}
+	}
+	r.userRoles[userID] = roles
+	return nil
+}
+
+// newTestTokenIssuer signs with a local HMAC key and uses the default TTLs.
+func newTestTokenIssuer() *utils.TokenIssuer {
+
+	cfg := config.Default()
+	cfg.OIDC.Issuer = "https://auth.example.com"
+	cfg.JWT.Audience = "https://auth.example.com"
+	cfg.RefreshToken.Pepper = "test-pepper"
+
+	return utils.NewTokenIssuer(cfg, utils.NewKeyring(utils.NewHMACSigningKey("test", []byte("test-secret"))))
+}
+
+// newTestAuthService returns the service with in-memory sessions and the
+// token blacklist in miniredis.
+func newTestAuthService(t *testing.T) (*AuthService, *fakeRefTokenRepository) {
+	t.Helper()
+
+	tokens := newTestTokenIssuer()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	tokenRepo := &fakeRefTokenRepository{sessions: map[string]*model.RefreshSession{}}
+	roleRepo := &fakeRoleRepository{userRoles: map[uuid.UUID][]string{}, rolePermissions: map[string][]string{}}
+	blacklist := NewBlacklistService(redisClient, tokens.AccessTokenTTL)
+
+	return NewAuthService(tokenRepo, roleRepo, blacklist, tokens, config.WebhookConfig{}), tokenRepo
+}
+
+func sessionContext(userAgent string) context.Context {
+	ctx := context.WithValue(context.Background(), ctxkeys.UserAgentKey, userAgent)
+	return context.WithValue(ctx, ctxkeys.IPAddressKey, testIPAddress)
+}
+
+func isBlacklisted(t *testing.T, s *AuthService, sessionID string) bool {
+	t.Helper()
+
+	blacklisted, err := s.Blacklist.IsTokenBlacklist(sessionID)
+	if err != nil {
+		t.Fatalf("IsTokenBlacklist: %v", err)
+	}
+	return blacklisted
+}
+
+func TestRefreshSessionReuse(t *testing.T) {
+
+	tests := []struct {
+		name string
+		// prepare returns the refresh token to present after the session
+		// was created with refreshToken.
+		prepare       func(t *testing.T, s *AuthService, refreshToken string) string
+		userAgent     string
+		wantErr       bool
+		revokesFamily bool
+	}{
+		{
+			name:      "current token",
+			prepare:   func(t *testing.T, s *AuthService, refreshToken string) string { return refreshToken },
+			userAgent: testUserAgent,
+		},
+		{
+			name: "rotated token",
+			prepare: func(t *testing.T, s *AuthService, refreshToken string) string {
+				if _, _, err := s.RefreshSession(sessionContext(testUserAgent), "", refreshToken); err != nil {
+					t.Fatalf("RefreshSession: %v", err)
+				}
+				return refreshToken
+			},
+			userAgent:     testUserAgent,
+			wantErr:       true,
+			revokesFamily: true,
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, s *AuthService, refreshToken string) string {
+				return "not-a-refresh-token"
+			},
+			userAgent: testUserAgent,
+			wantErr:   true,
+		},
+		{
+			name:      "other user agent",
+			prepare:   func(t *testing.T, s *AuthService, refreshToken string) string { return refreshToken },
+			userAgent: "other-agent",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, tokenRepo := newTestAuthService(t)
+			userID := uuid.New()
+
+			_, refreshToken, err := s.NewSession(sessionContext(testUserAgent), userID, []string{AMRPassword})
+			if err != nil {
+				t.Fatalf("NewSession: %v", err)
+			}
+			presented := tt.prepare(t, s, refreshToken)
+
+			_, _, err = s.RefreshSession(sessionContext(tt.userAgent), "", presented)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RefreshSession error = %v, want error %v", err, tt.wantErr)
+			}
+			if appErr, ok := errors.IsAppError(err); err != nil && (!ok || appErr.Type != errors.ErrorTypeAuth) {
+				t.Errorf("RefreshSession error = %v, want authentication error", err)
+			}
+
+			active, _ := tokenRepo.GetActiveUserSessions(context.Background(), userID)
+			if tt.revokesFamily {
+				if len(active) != 0 {
+					t.Errorf("%d sessions of the family are still active", len(active))
+				}
+				// The rotated session's access token expires on its own.
+				for _, refSession := range tokenRepo.sessions {
+					if !refSession.Rotated && !isBlacklisted(t, s, refSession.SessionID) {
+						t.Errorf("access token of session %s is not blacklisted", refSession.SessionID)
+					}
+				}
+			} else if !tt.wantErr && len(active) != 1 {
+				t.Errorf("%d active sessions after refresh, want 1", len(active))
+			}
+		})
+	}
+}
+
+func TestRefreshSessionLegacyHash(t *testing.T) {
+
+	const legacyToken = "legacy-refresh-token"
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte(legacyToken), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		withAccess   bool
+		wantErr      bool
+	}{
+		{name: "with access token", refreshToken: legacyToken, withAccess: true},
+		{name: "without access token", refreshToken: legacyToken, wantErr: true},
+		{name: "wrong refresh token", refreshToken: "other-refresh-token", withAccess: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, tokenRepo := newTestAuthService(t)
+			userID := uuid.New()
+			sessionID := uuid.NewString()
+			tokenRepo.Create(context.Background(), &model.RefreshSession{
+				SessionID:        sessionID,
+				UserID:           userID,
+				FamilyID:         sessionID,
+				RefreshTokenHash: string(legacyHash),
+				UserAgent:        testUserAgent,
+				IPAddress:        testIPAddress,
+				CreatedAt:        time.Now(),
+				RefreshedAt:      time.Now(),
+			})
+
+			var accessToken string
+			if tt.withAccess {
+				accessToken, err = s.Tokens.GenerateJWT(utils.AccessTokenClaims{UserID: userID.String(), SessionID: sessionID})
+				if err != nil {
+					t.Fatalf("GenerateJWT: %v", err)
+				}
+			}
+
+			_, refreshToken, err := s.RefreshSession(sessionContext(testUserAgent), accessToken, tt.refreshToken)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RefreshSession error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// The successor is stored with the keyed hash and found by it.
+			hash, err := s.Tokens.HashRefreshToken(refreshToken)
+			if err != nil {
+				t.Fatalf("HashRefreshToken: %v", err)
+			}
+			successor, err := tokenRepo.FindRefreshSessionByTokenHash(context.Background(), hash)
+			if err != nil {
+				t.Fatalf("successor session not found by keyed hash: %v", err)
+			}
+			if successor.FamilyID != sessionID {
+				t.Errorf("successor family = %s, want %s", successor.FamilyID, sessionID)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"authservice/internal/errors"
+	"authservice/internal/repository"
 	"context"
 	"time"
 
@@ -8,12 +10,14 @@ import (
 )
 
 type BlacklistService struct {
-	Cache *redis.Client
+	Cache          *redis.Client
+	AccessTokenTTL time.Duration
 }
 
-func NewBlacklistService(redis *redis.Client) *BlacklistService {
+func NewBlacklistService(redis *redis.Client, accessTokenTTL time.Duration) *BlacklistService {
 	return &BlacklistService{
-		Cache: redis,
+		Cache:          redis,
+		AccessTokenTTL: accessTokenTTL,
 	}
 }
 
@@ -28,3 +32,27 @@ func (s *BlacklistService) IsTokenBlacklist(sid string) (bool, error) {
 	res, err := s.Cache.Exists(context.Background(), key).Result()
 	return res == 1, err
 }
+
+// blacklistLiveSessions includes sessions replaced by a refresh, since their
+// access tokens stay valid until they expire.
+func blacklistLiveSessions(ctx context.Context, tokenRepo repository.IRefTokenRepository, blacklist *BlacklistService, filter repository.SessionFilter) error {
+
+	filter.IncludeRevoked = true
+	filter.RefreshedAfter = time.Now().Add(-blacklist.AccessTokenTTL)
+	refSessions, err := tokenRepo.SearchSessions(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, refSession := range refSessions {
+		ttl := time.Until(refSession.RefreshedAt.Add(blacklist.AccessTokenTTL))
+		if ttl <= 0 {
+			continue
+		}
+		if err := blacklist.AddToken(refSession.SessionID, ttl); err != nil {
+			return errors.NewError(errors.ErrorTypeRedis, "failed add token blacklist", err)
+		}
+	}
+
+	return nil
+}
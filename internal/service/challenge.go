@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type ChallengeStore interface {
+	Save(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	SaveIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Take returns the value and deletes it, so every challenge can be
+	// answered only once. It returns redis.Nil when the key does not exist.
+	Take(ctx context.Context, key string) ([]byte, error)
+}
+
+type RedisChallengeStore struct {
+	Cache *redis.Client
+}
+
+func NewRedisChallengeStore(redis *redis.Client) *RedisChallengeStore {
+	return &RedisChallengeStore{
+		Cache: redis,
+	}
+}
+
+func (s *RedisChallengeStore) Save(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.Cache.Set(ctx, "ch:"+key, value, ttl).Err()
+}
+
+func (s *RedisChallengeStore) SaveIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return s.Cache.SetNX(ctx, "ch:"+key, value, ttl).Result()
+}
+
+func (s *RedisChallengeStore) Take(ctx context.Context, key string) ([]byte, error) {
+	return s.Cache.GetDel(ctx, "ch:"+key).Bytes()
+}
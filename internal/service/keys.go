@@ -0,0 +1,159 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"authservice/internal/utils"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// A new key is published one sync interval before it signs tokens, so by then
+// every replica accepts it.
+const keySyncInterval = time.Minute
+
+type KeyService struct {
+	Tokens        *utils.TokenIssuer
+	KeyRepo       repository.ISigningKeyRepository
+	encryptionKey []byte
+	configured    []*utils.SigningKey
+}
+
+func NewKeyService(tokens *utils.TokenIssuer, keyRepo repository.ISigningKeyRepository, encryptionKey []byte) *KeyService {
+	return &KeyService{
+		Tokens:        tokens,
+		KeyRepo:       keyRepo,
+		encryptionKey: encryptionKey,
+		configured:    tokens.Keyring.Keys(),
+	}
+}
+
+func (s *KeyService) Sync(ctx context.Context) error {
+
+	stored, err := s.KeyRepo.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var active *utils.SigningKey
+	var firstActivation time.Time
+	retired := make([]utils.RetiredKey, 0, len(stored)+len(s.configured))
+	for _, storedKey := range stored {
+		key, err := utils.ParseStoredKey(s.encryptionKey, storedKey.KeyID, storedKey.Algorithm, storedKey.PrivateKey)
+		if err != nil {
+			return err
+		}
+
+		if !storedKey.ActivateAt.After(now) {
+			// Keys are listed newest first.
+			firstActivation = storedKey.ActivateAt
+			if active == nil {
+				active = key
+				continue
+			}
+		}
+
+		var retireAt time.Time
+		if storedKey.RetireAt != nil {
+			retireAt = *storedKey.RetireAt
+		}
+		retired = append(retired, utils.RetiredKey{Key: key, RetireAt: retireAt})
+	}
+
+	// Configured keys are accepted only until their tokens expire, so a leaked
+	// configured key stops working after the first rotation.
+	configured := s.configured
+	var configuredRetireAt time.Time
+	if active == nil {
+		active, configured = configured[0], configured[1:]
+	} else {
+		configuredRetireAt = firstActivation.Add(keySyncInterval + s.Tokens.AccessTokenTTL)
+	}
+	for _, key := range configured {
+		retired = append(retired, utils.RetiredKey{Key: key, RetireAt: configuredRetireAt})
+	}
+
+	s.Tokens.Keyring.Replace(active, retired)
+	return nil
+}
+
+func (s *KeyService) Rotate(ctx context.Context) (string, error) {
+
+	keyID, err := s.rotate(ctx, time.Time{})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.Sync(ctx); err != nil {
+		return "", err
+	}
+
+	return keyID, nil
+}
+
+func (s *KeyService) rotate(ctx context.Context, createdAfter time.Time) (string, error) {
+
+	previous := s.Tokens.Keyring.Active()
+
+	next, err := utils.GenerateSigningKey(previous.Method)
+	if err != nil {
+		return "", errors.NewError(errors.ErrorTypeInternal, "failed generate signing key", err)
+	}
+
+	privateKey, err := utils.SealPrivateKey(s.encryptionKey, next)
+	if err != nil {
+		return "", err
+	}
+
+	// Replicas switch to the new key on their next sync, so the previous
+	// key may sign tokens until one sync interval after activation.
+	now := time.Now()
+	activateAt := now.Add(keySyncInterval)
+	retirePrevious := activateAt.Add(keySyncInterval + s.Tokens.AccessTokenTTL)
+
+	created, err := s.KeyRepo.CreateSigningKey(ctx, &model.SigningKey{
+		KeyID:      next.ID,
+		Algorithm:  next.Method.Alg(),
+		PrivateKey: privateKey,
+		CreatedAt:  now,
+		ActivateAt: activateAt,
+	}, retirePrevious, createdAfter)
+	if err != nil {
+		return "", err
+	}
+	if !created {
+		return "", nil
+	}
+
+	slog.Info("Rotated JWT signing key", "previous_kid", previous.ID, "kid", next.ID, "activate_at", activateAt)
+	return next.ID, nil
+}
+
+func (s *KeyService) Run(ctx context.Context, rotationInterval time.Duration) {
+
+	started := time.Now()
+	ticker := time.NewTicker(keySyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if rotationInterval > 0 && time.Since(started) >= rotationInterval {
+				if _, err := s.rotate(ctx, time.Now().Add(-rotationInterval)); err != nil {
+					slog.Error("Failed scheduled key rotation", "error", err)
+				}
+			}
+			if err := s.Sync(ctx); err != nil {
+				slog.Error("Failed to load signing keys", "error", err)
+			}
+			if err := s.KeyRepo.DeleteRetiredSigningKeys(ctx); err != nil {
+				slog.Error("Failed to delete retired signing keys", "error", err)
+			}
+		}
+	}
+}
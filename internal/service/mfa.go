@@ -0,0 +1,240 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"authservice/internal/utils"
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const recoveryCodeCount = 10
+
+const (
+	mfaTokenMaxAttempts = 5
+	mfaUserMaxFailures  = 10
+	mfaUserLockout      = 15 * time.Minute
+)
+
+// AMRRecoveryCode is not registered in RFC 8176.
+const (
+	AMRPassword     = "pwd"
+	AMROTP          = "otp"
+	AMRMFA          = "mfa"
+	AMRRecoveryCode = "recovery_code"
+)
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type MFAService struct {
+	MFARepo    repository.IMFARepository
+	UserRepo   repository.IUserRepository
+	Cache      *redis.Client
+	Tokens     *utils.TokenIssuer
+	TOTPIssuer string
+}
+
+func NewMFAService(mfaRepo repository.IMFARepository, userRepo repository.IUserRepository, redis *redis.Client, tokens *utils.TokenIssuer, totpIssuer string) *MFAService {
+	return &MFAService{
+		MFARepo:    mfaRepo,
+		UserRepo:   userRepo,
+		Cache:      redis,
+		Tokens:     tokens,
+		TOTPIssuer: totpIssuer,
+	}
+}
+
+func (s *MFAService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+
+	user, err := s.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.MFARepo.SaveTOTP(ctx, &model.TOTP{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+
+	totp, err := s.MFARepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp.ConfirmedAt != nil {
+		return nil, errors.NewError(errors.ErrorTypeConflict, "TOTP already enrolled", nil)
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid TOTP code", nil)
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hash, err := s.Tokens.HashRecoveryCode(code)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "failed hash recovery code", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := s.MFARepo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *MFAService) IsTOTPEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+
+	totp, err := s.MFARepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return totp.ConfirmedAt != nil, nil
+}
+
+func (s *MFAService) VerifySecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) error {
+
+	if recoveryCode != "" {
+		hash, err := s.Tokens.HashRecoveryCode(recoveryCode)
+		if err != nil {
+			return errors.NewError(errors.ErrorTypeInternal, "failed hash recovery code", err)
+		}
+		return s.MFARepo.UseRecoveryCode(ctx, userID, hash)
+	}
+
+	totp, err := s.MFARepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if totp.ConfirmedAt == nil {
+		return errors.NewError(errors.ErrorTypeAuth, "TOTP not enrolled", nil)
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return errors.NewError(errors.ErrorTypeAuth, "invalid TOTP code", nil)
+	}
+
+	return s.MFARepo.UseTOTPStep(ctx, userID, step)
+}
+
+func (s *MFAService) CompleteLogin(ctx context.Context, mfaToken, code, recoveryCode string) (uuid.UUID, []string, error) {
+
+	userIDStr, tokenID, err := s.Tokens.ParseMFAToken(mfaToken)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "invalid MFA token", err)
+	}
+
+	usedKey := "mfa:used:" + tokenID
+	attemptsKey := "mfa:attempts:" + tokenID
+	failuresKey := "mfa:failures:" + userID.String()
+
+	failures, err := s.Cache.Get(ctx, failuresKey).Int()
+	if err != nil && !stderrors.Is(err, redis.Nil) {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeRedis, "failed get MFA failures", err)
+	}
+	if failures >= mfaUserMaxFailures {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "too many failed MFA attempts, try again later", nil)
+	}
+
+	used, err := s.Cache.Exists(ctx, usedKey).Result()
+	if err != nil {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeRedis, "failed check MFA token", err)
+	}
+	if used == 1 {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "MFA token already used", nil)
+	}
+
+	// Attempts are counted before the check, so parallel requests cannot
+	// exceed the limit.
+	attempts, err := s.Cache.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeRedis, "failed count MFA attempts", err)
+	}
+	if attempts == 1 {
+		if err := s.Cache.Expire(ctx, attemptsKey, utils.MFATokenTTL).Err(); err != nil {
+			return uuid.Nil, nil, errors.NewError(errors.ErrorTypeRedis, "failed count MFA attempts", err)
+		}
+	}
+	if attempts > mfaTokenMaxAttempts {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "too many failed attempts for MFA token", nil)
+	}
+
+	if err := s.VerifySecondFactor(ctx, userID, code, recoveryCode); err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeAuth {
+			if err := s.recordFailure(ctx, failuresKey); err != nil {
+				return uuid.Nil, nil, err
+			}
+		}
+		return uuid.Nil, nil, err
+	}
+
+	ok, err := s.Cache.SetNX(ctx, usedKey, "used", utils.MFATokenTTL).Result()
+	if err != nil {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeRedis, "failed use MFA token", err)
+	}
+	if !ok {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "MFA token already used", nil)
+	}
+
+	if recoveryCode != "" {
+		return userID, []string{AMRPassword, AMRRecoveryCode, AMRMFA}, nil
+	}
+	return userID, []string{AMRPassword, AMROTP, AMRMFA}, nil
+}
+
+func (s *MFAService) recordFailure(ctx context.Context, failuresKey string) error {
+
+	failures, err := s.Cache.Incr(ctx, failuresKey).Result()
+	if err != nil {
+		return errors.NewError(errors.ErrorTypeRedis, "failed count MFA failures", err)
+	}
+	if failures == 1 {
+		if err := s.Cache.Expire(ctx, failuresKey, mfaUserLockout).Err(); err != nil {
+			return errors.NewError(errors.ErrorTypeRedis, "failed count MFA failures", err)
+		}
+	}
+
+	return nil
+}
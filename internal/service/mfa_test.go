@@ -0,0 +1,209 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeMFARepository struct {
+	totp          *model.TOTP
+	recoveryCodes map[string]bool
+}
+
+func (r *fakeMFARepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error) {
+	if r.totp == nil || r.totp.UserID != userID {
+		return nil, errors.NewError(errors.ErrorTypeNotFound, "TOTP not enrolled", nil)
+	}
+	totp := *r.totp
+	return &totp, nil
+}
+
+func (r *fakeMFARepository) SaveTOTP(ctx context.Context, totp *model.TOTP) error {
+	r.totp = totp
+	return nil
+}
+
+func (r *fakeMFARepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	now := time.Now()
+	r.totp.ConfirmedAt = &now
+	r.totp.LastUsedStep = step
+	r.recoveryCodes = map[string]bool{}
+	for _, hash := range recoveryCodeHashes {
+		r.recoveryCodes[hash] = false
+	}
+	return nil
+}
+
+func (r *fakeMFARepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	if r.totp.ConfirmedAt == nil || r.totp.LastUsedStep >= step {
+		return errors.NewError(errors.ErrorTypeAuth, "TOTP code already used", nil)
+	}
+	r.totp.LastUsedStep = step
+	return nil
+}
+
+func (r *fakeMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	if used, ok := r.recoveryCodes[codeHash]; !ok || used {
+		return errors.NewError(errors.ErrorTypeAuth, "invalid recovery code", nil)
+	}
+	r.recoveryCodes[codeHash] = true
+	return nil
+}
+
+// currentTOTPCode computes the RFC 6238 code an authenticator app shows now.
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode TOTP secret: %v", err)
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// newTestMFAService returns the service with a user who confirmed TOTP
+// enrollment, the valid code and one recovery code.
+func newTestMFAService(t *testing.T) (*MFAService, uuid.UUID, string, string) {
+	t.Helper()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	user := &model.User{ID: uuid.New(), Email: "user@example.com"}
+	userRepo := &fakeUserRepository{users: map[uuid.UUID]*model.User{user.ID: user}}
+	s := NewMFAService(&fakeMFARepository{}, userRepo, redisClient, newTestTokenIssuer(), "Auth Service")
+
+	enrollment, err := s.EnrollTOTP(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	code := currentTOTPCode(t, enrollment.Secret)
+	recoveryCodes, err := s.ConfirmTOTP(context.Background(), user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+
+	// ConfirmTOTP used the current step, so logins start from the next one.
+	s.MFARepo.(*fakeMFARepository).totp.LastUsedStep--
+
+	return s, user.ID, code, recoveryCodes[0]
+}
+
+func TestCompleteLogin(t *testing.T) {
+
+	const validCode = "<valid>"
+	const recoveryCode = "<recovery>"
+
+	type attempt struct {
+		newToken     bool
+		code         string
+		recoveryCode string
+		wantErr      bool
+	}
+
+	tests := []struct {
+		name     string
+		attempts []attempt
+		wantAMR  []string
+	}{
+		{
+			name:     "valid code",
+			attempts: []attempt{{newToken: true, code: validCode}},
+			wantAMR:  []string{AMRPassword, AMROTP, AMRMFA},
+		},
+		{
+			name:     "recovery code",
+			attempts: []attempt{{newToken: true, recoveryCode: recoveryCode}},
+			wantAMR:  []string{AMRPassword, AMRRecoveryCode, AMRMFA},
+		},
+		{
+			name: "reused step",
+			attempts: []attempt{
+				{newToken: true, code: validCode},
+				{newToken: true, code: validCode, wantErr: true},
+			},
+		},
+		{
+			name: "reused recovery code",
+			attempts: []attempt{
+				{newToken: true, recoveryCode: recoveryCode},
+				{newToken: true, recoveryCode: recoveryCode, wantErr: true},
+			},
+		},
+		{
+			name: "reused MFA token",
+			attempts: []attempt{
+				{newToken: true, recoveryCode: recoveryCode},
+				{code: validCode, wantErr: true},
+			},
+		},
+		{
+			name: "token attempt limit",
+			attempts: append(slices.Repeat([]attempt{{code: "000000", wantErr: true}}, mfaTokenMaxAttempts),
+				attempt{code: validCode, wantErr: true}),
+		},
+		{
+			name: "user lockout",
+			attempts: append(slices.Repeat([]attempt{{newToken: true, code: "000000", wantErr: true}}, mfaUserMaxFailures),
+				attempt{newToken: true, code: validCode, wantErr: true}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, userID, code, recovery := newTestMFAService(t)
+
+			var mfaToken string
+			for i, a := range tt.attempts {
+				if a.newToken || mfaToken == "" {
+					var err error
+					if mfaToken, err = s.Tokens.GenerateMFAToken(userID.String()); err != nil {
+						t.Fatalf("GenerateMFAToken: %v", err)
+					}
+				}
+				if a.code == validCode {
+					a.code = code
+				}
+				if a.recoveryCode == recoveryCode {
+					a.recoveryCode = recovery
+				}
+
+				gotUserID, amr, err := s.CompleteLogin(context.Background(), mfaToken, a.code, a.recoveryCode)
+				if (err != nil) != a.wantErr {
+					t.Fatalf("attempt %d: CompleteLogin error = %v, want error %v", i, err, a.wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				if gotUserID != userID {
+					t.Errorf("attempt %d: user = %s, want %s", i, gotUserID, userID)
+				}
+				if tt.wantAMR != nil && !slices.Equal(amr, tt.wantAMR) {
+					t.Errorf("attempt %d: amr = %v, want %v", i, amr, tt.wantAMR)
+				}
+			}
+		})
+	}
+}
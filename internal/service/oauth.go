@@ -0,0 +1,797 @@
+package service
+
+import (
+	"authservice/internal/authctx"
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"authservice/internal/utils"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const authorizationCodeTTL = time.Minute
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+const ClientAssertionTypeJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+const ScopeOpenID = "openid"
+
+const ScopeEmail = "email"
+
+var defaultClientScopes = []string{ScopeOpenID, ScopeEmail}
+
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthAccessDenied            = "access_denied"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthServerError             = "server_error"
+	// OpenID Connect Core section 3.1.2.6.
+	OAuthLoginRequired   = "login_required"
+	OAuthConsentRequired = "consent_required"
+)
+
+const (
+	PromptNone    = "none"
+	PromptConsent = "consent"
+)
+
+func HasPrompt(prompt, value string) bool {
+	return slices.Contains(strings.Fields(prompt), value)
+}
+
+func oauthError(errorType errors.ErrorType, code, message string, err error) *errors.AppError {
+	appErr := errors.NewError(errorType, message, err)
+	appErr.Code = code
+	return appErr
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	Prompt              string
+}
+
+type ClientCredentials struct {
+	ClientID            string
+	ClientSecret        string
+	ClientAssertionType string
+	ClientAssertion     string
+}
+
+type TokenRequest struct {
+	ClientCredentials
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type RegisteredClient struct {
+	ClientID               string   `json:"client_id"`
+	ClientSecret           string   `json:"client_secret,omitempty"`
+	Name                   string   `json:"name"`
+	RedirectURIs           []string `json:"redirect_uris"`
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris"`
+	GrantTypes             []string `json:"grant_types"`
+	Scopes                 []string `json:"scopes"`
+	Audiences              []string `json:"audiences"`
+}
+
+type authorizationCode struct {
+	ClientID      string    `json:"client_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	Scope         string    `json:"scope"`
+	CodeChallenge string    `json:"code_challenge"`
+	Nonce         string    `json:"nonce,omitempty"`
+	AuthTime      time.Time `json:"auth_time"`
+	AMR           []string  `json:"amr"`
+}
+
+type OAuthService struct {
+	Tokens      *utils.TokenIssuer
+	AuthService *AuthService
+	ClientRepo  repository.IOAuthClientRepository
+	Codes       ChallengeStore
+}
+
+func NewOAuthService(tokens *utils.TokenIssuer, authService *AuthService, clientRepo repository.IOAuthClientRepository, codes ChallengeStore) *OAuthService {
+	return &OAuthService{
+		Tokens:      tokens,
+		AuthService: authService,
+		ClientRepo:  clientRepo,
+		Codes:       codes,
+	}
+}
+
+type ClientGrant struct {
+	Scopes    []string
+	Audiences []string
+}
+
+func (s *OAuthService) RegisterClient(ctx context.Context, name string, redirectURIs, postLogoutRedirectURIs []string, grant ClientGrant, confidential bool) (*RegisteredClient, error) {
+
+	if name == "" || len(redirectURIs) == 0 {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "name and redirect_uris are required", nil)
+	}
+	if grant.Scopes == nil {
+		grant.Scopes = defaultClientScopes
+	}
+	if err := validateClientGrant(&grant); err != nil {
+		return nil, err
+	}
+	if postLogoutRedirectURIs == nil {
+		postLogoutRedirectURIs = []string{}
+	}
+	for _, redirectURI := range append(slices.Clone(redirectURIs), postLogoutRedirectURIs...) {
+		if !validRedirectURI(redirectURI) {
+			return nil, errors.NewError(errors.ErrorTypeValidation, "invalid redirect URI "+redirectURI, nil)
+		}
+	}
+
+	client := &model.OAuthClient{
+		ClientID:               uuid.New().String(),
+		Name:                   name,
+		RedirectURIs:           redirectURIs,
+		PostLogoutRedirectURIs: postLogoutRedirectURIs,
+		GrantTypes:             []string{GrantAuthorizationCode, GrantRefreshToken},
+		Scopes:                 grant.Scopes,
+		Audiences:              grant.Audiences,
+		CreatedAt:              time.Now(),
+	}
+
+	var secret string
+	if confidential {
+		var err error
+		secret, client.ClientSecretHash, err = s.generateClientSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ClientRepo.Create(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return newRegisteredClient(client, secret), nil
+}
+
+func newRegisteredClient(client *model.OAuthClient, secret string) *RegisteredClient {
+	return &RegisteredClient{
+		ClientID:               client.ClientID,
+		ClientSecret:           secret,
+		Name:                   client.Name,
+		RedirectURIs:           client.RedirectURIs,
+		PostLogoutRedirectURIs: client.PostLogoutRedirectURIs,
+		GrantTypes:             client.GrantTypes,
+		Scopes:                 client.Scopes,
+		Audiences:              client.Audiences,
+	}
+}
+
+func validateClientGrant(grant *ClientGrant) error {
+
+	if grant.Scopes == nil {
+		grant.Scopes = []string{}
+	}
+	if grant.Audiences == nil {
+		grant.Audiences = []string{}
+	}
+
+	for _, scope := range grant.Scopes {
+		if scope == "" {
+			return errors.NewError(errors.ErrorTypeValidation, "scopes must not be empty", nil)
+		}
+		for _, r := range scope {
+			if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+				return errors.NewError(errors.ErrorTypeValidation, "invalid scope "+scope, nil)
+			}
+		}
+	}
+	for _, audience := range grant.Audiences {
+		if strings.TrimSpace(audience) == "" {
+			return errors.NewError(errors.ErrorTypeValidation, "audiences must not be empty", nil)
+		}
+	}
+
+	return nil
+}
+
+func grantedScope(client *model.OAuthClient, requested string) (string, error) {
+
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return strings.Join(client.Scopes, " "), nil
+	}
+
+	granted := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return "", oauthError(errors.ErrorTypeValidation, OAuthInvalidScope, "scope "+scope+" is not allowed for the client", nil)
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	return strings.Join(granted, " "), nil
+}
+
+// validRedirectURI accepts absolute URIs without a fragment (RFC 6749
+// section 3.1.2). Native apps may use private-use schemes without a host.
+func validRedirectURI(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Scheme == "" || u.Fragment != "" {
+		return false
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return false
+	}
+	return true
+}
+
+// AuthorizeClient must succeed before errors may be sent to the redirect URI;
+// until then they are shown to the user.
+func (s *OAuthService) AuthorizeClient(ctx context.Context, clientID, redirectURI string) (*model.OAuthClient, error) {
+
+	if clientID == "" || redirectURI == "" {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "client_id and redirect_uri are required", nil)
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, clientID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return nil, errors.NewError(errors.ErrorTypeValidation, "unknown client_id", err)
+		}
+		return nil, err
+	}
+
+	// Redirect URIs are compared exactly, as required by OAuth 2.1.
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "redirect_uri is not registered for the client", nil)
+	}
+
+	return client, nil
+}
+
+// Authorize requires PKCE with S256 for every client.
+func (s *OAuthService) Authorize(ctx context.Context, claims *authctx.Claims, client *model.OAuthClient, req AuthorizeRequest) (string, error) {
+
+	if req.ResponseType != "code" {
+		return "", oauthError(errors.ErrorTypeValidation, OAuthUnsupportedResponseType, "only the code response type is supported", nil)
+	}
+	if !slices.Contains(client.GrantTypes, GrantAuthorizationCode) {
+		return "", oauthError(errors.ErrorTypeValidation, OAuthUnauthorizedClient, "client may not use the authorization code grant", nil)
+	}
+	if req.CodeChallengeMethod != "S256" || !utils.ValidPKCEVerifier(req.CodeChallenge) {
+		return "", oauthError(errors.ErrorTypeValidation, OAuthInvalidRequest, "code_challenge with the S256 method is required", nil)
+	}
+
+	scope, err := grantedScope(client, req.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.requireConsent(ctx, claims.UserID, client.ClientID, scope, req.Prompt); err != nil {
+		return "", err
+	}
+
+	authTime, err := s.AuthService.SessionAuthTime(ctx, claims.SessionID)
+	if err != nil {
+		return "", oauthError(errors.ErrorTypeAuth, OAuthAccessDenied, "failed get session", err)
+	}
+
+	code, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", oauthError(errors.ErrorTypeInternal, OAuthServerError, "failed generate authorization code", err)
+	}
+
+	data, err := json.Marshal(authorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        claims.UserID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		AuthTime:      authTime,
+		AMR:           claims.AMR,
+	})
+	if err != nil {
+		return "", oauthError(errors.ErrorTypeInternal, OAuthServerError, "failed marshal authorization code", err)
+	}
+
+	if err := s.Codes.Save(ctx, "code:"+code, data, authorizationCodeTTL); err != nil {
+		return "", oauthError(errors.ErrorTypeRedis, OAuthServerError, "failed store authorization code", err)
+	}
+
+	return code, nil
+}
+
+func (s *OAuthService) requireConsent(ctx context.Context, userID uuid.UUID, clientID, scope, prompt string) error {
+
+	consentRequired := oauthError(errors.ErrorTypeForbidden, OAuthConsentRequired, "user consent required", nil)
+	if HasPrompt(prompt, PromptConsent) {
+		return consentRequired
+	}
+
+	consent, err := s.ClientRepo.GetConsent(ctx, userID, clientID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return consentRequired
+		}
+		return oauthError(errors.ErrorTypeDatabase, OAuthServerError, "failed get consent", err)
+	}
+
+	for _, scope := range strings.Fields(scope) {
+		if !slices.Contains(consent.Scopes, scope) {
+			return consentRequired
+		}
+	}
+
+	return nil
+}
+
+func (s *OAuthService) GrantConsent(ctx context.Context, claims *authctx.Claims, client *model.OAuthClient, req AuthorizeRequest, approved bool) error {
+
+	if !approved {
+		return oauthError(errors.ErrorTypeForbidden, OAuthAccessDenied, "the user denied access", nil)
+	}
+
+	scope, err := grantedScope(client, req.Scope)
+	if err != nil {
+		return err
+	}
+	scopes := strings.Fields(scope)
+
+	previous, err := s.ClientRepo.GetConsent(ctx, claims.UserID, client.ClientID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); !ok || appErr.Type != errors.ErrorTypeNotFound {
+			return err
+		}
+	} else {
+		for _, scope := range previous.Scopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return s.ClientRepo.SaveConsent(ctx, &model.OAuthConsent{
+		UserID:    claims.UserID,
+		ClientID:  client.ClientID,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *OAuthService) Token(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+
+	client, err := s.authenticateClient(ctx, req.ClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials:
+	default:
+		return nil, oauthError(errors.ErrorTypeValidation, OAuthUnsupportedGrantType, "unsupported grant type", nil)
+	}
+	if !slices.Contains(client.GrantTypes, req.GrantType) {
+		return nil, oauthError(errors.ErrorTypeValidation, OAuthUnauthorizedClient, "client may not use this grant type", nil)
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		return s.exchangeCode(ctx, client, req)
+	case GrantClientCredentials:
+		return s.issueClientToken(client, req.Scope)
+	}
+
+	if req.RefreshToken == "" {
+		return nil, oauthError(errors.ErrorTypeValidation, OAuthInvalidRequest, "refresh_token is required", nil)
+	}
+
+	issued, err := s.AuthService.RefreshClientSession(ctx, client.ClientID, req.RefreshToken)
+	if err != nil {
+		return nil, invalidGrant(err)
+	}
+
+	return s.newTokenResponse(issued), nil
+}
+
+func (s *OAuthService) exchangeCode(ctx context.Context, client *model.OAuthClient, req TokenRequest) (*TokenResponse, error) {
+
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		return nil, oauthError(errors.ErrorTypeValidation, OAuthInvalidRequest, "code, redirect_uri and code_verifier are required", nil)
+	}
+
+	data, err := s.Codes.Take(ctx, "code:"+req.Code)
+	if err != nil {
+		if stdErrors.Is(err, redis.Nil) {
+			return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidGrant, "authorization code is invalid or expired", nil)
+		}
+		return nil, oauthError(errors.ErrorTypeRedis, OAuthServerError, "failed load authorization code", err)
+	}
+
+	var code authorizationCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, oauthError(errors.ErrorTypeInternal, OAuthServerError, "failed unmarshal authorization code", err)
+	}
+
+	if code.ClientID != client.ClientID {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidGrant, "authorization code was issued to another client", nil)
+	}
+	if code.RedirectURI != req.RedirectURI {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidGrant, "redirect_uri does not match the authorization request", nil)
+	}
+	if !utils.VerifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidGrant, "code_verifier does not match the code challenge", nil)
+	}
+
+	issued, err := s.AuthService.NewClientSession(ctx, code.UserID, SessionGrant{
+		ClientID: client.ClientID,
+		Scope:    code.Scope,
+		Audience: client.Audiences,
+		AMR:      code.AMR,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.newTokenResponse(issued)
+	if slices.Contains(strings.Fields(code.Scope), ScopeOpenID) {
+		response.IDToken, err = s.Tokens.GenerateIDToken(utils.IDToken{
+			Issuer:      s.Tokens.Issuer,
+			UserID:      code.UserID.String(),
+			ClientID:    client.ClientID,
+			SessionID:   issued.SessionID,
+			Nonce:       code.Nonce,
+			AuthTime:    code.AuthTime,
+			AMR:         code.AMR,
+			AccessToken: issued.AccessToken,
+		})
+		if err != nil {
+			return nil, oauthError(errors.ErrorTypeInternal, OAuthServerError, "failed generate ID token", err)
+		}
+	}
+
+	return response, nil
+}
+
+func (s *OAuthService) issueClientToken(client *model.OAuthClient, requestedScope string) (*TokenResponse, error) {
+
+	scope, err := grantedScope(client, requestedScope)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.Tokens.GenerateJWT(utils.AccessTokenClaims{
+		ClientID: client.ClientID,
+		Scope:    scope,
+		Audience: client.Audiences,
+	})
+	if err != nil {
+		return nil, oauthError(errors.ErrorTypeInternal, OAuthServerError, "failed generate access token", err)
+	}
+
+	return s.newTokenResponse(&IssuedSession{AccessToken: accessToken, Scope: scope}), nil
+}
+
+func (s *OAuthService) authenticateClient(ctx context.Context, req ClientCredentials) (*model.OAuthClient, error) {
+
+	clientID := req.ClientID
+	if req.ClientAssertion != "" {
+		if req.ClientAssertionType != ClientAssertionTypeJWT {
+			return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "unsupported client_assertion_type", nil)
+		}
+		// The client is identified by the assertion itself when client_id
+		// is omitted (RFC 7523 section 3).
+		if clientID == "" {
+			clientID = utils.UnverifiedSubject(req.ClientAssertion)
+		}
+	}
+
+	if clientID == "" {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client_id is required", nil)
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, clientID)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "unknown client", err)
+		}
+		return nil, err
+	}
+
+	if client.PublicKey != "" {
+		if req.ClientAssertion == "" {
+			return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client must authenticate with a client assertion", nil)
+		}
+		if err := s.verifyClientAssertion(ctx, client, req.ClientAssertion); err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	if req.ClientAssertion != "" {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client has no registered public key", nil)
+	}
+
+	if client.ClientSecretHash == "" {
+		if req.ClientSecret != "" {
+			return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "public client must not send a secret", nil)
+		}
+		return client, nil
+	}
+
+	if req.ClientSecret == "" || !s.Tokens.CheckClientSecret(req.ClientSecret, client.ClientSecretHash) {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "invalid client credentials", nil)
+	}
+
+	return client, nil
+}
+
+// verifyClientAssertion requires the client's signature, the client as iss
+// and sub, this server as aud and a jti never seen before.
+func (s *OAuthService) verifyClientAssertion(ctx context.Context, client *model.OAuthClient, assertion string) error {
+
+	key, err := utils.ParsePublicKey([]byte(client.PublicKey))
+	if err != nil {
+		return oauthError(errors.ErrorTypeInternal, OAuthServerError, "invalid public key registered for client", err)
+	}
+
+	claims, err := utils.ParseClientAssertion(assertion, key)
+	if err != nil {
+		return oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "invalid client assertion", err)
+	}
+
+	iss, _ := claims.GetIssuer()
+	sub, _ := claims.GetSubject()
+	if iss != client.ClientID || sub != client.ClientID {
+		return oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client assertion must name the client as iss and sub", nil)
+	}
+
+	audience, _ := claims.GetAudience()
+	if !slices.Contains(audience, s.Tokens.Issuer) && !slices.Contains(audience, s.Tokens.Issuer+"/oauth/token") {
+		return oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client assertion has a wrong audience", nil)
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client assertion must have a jti", nil)
+	}
+
+	exp, _ := claims.GetExpirationTime()
+	fresh, err := s.Codes.SaveIfAbsent(ctx, "jti:"+client.ClientID+":"+jti, []byte{1}, time.Until(exp.Time))
+	if err != nil {
+		return oauthError(errors.ErrorTypeRedis, OAuthServerError, "failed store client assertion ID", err)
+	}
+	if !fresh {
+		return oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "client assertion was already used", nil)
+	}
+
+	return nil
+}
+
+func (s *OAuthService) CreateServiceAccount(ctx context.Context, name, publicKey string, grant ClientGrant) (*RegisteredClient, error) {
+
+	if name == "" {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "name is required", nil)
+	}
+	if err := validateClientGrant(&grant); err != nil {
+		return nil, err
+	}
+	if publicKey != "" {
+		if _, err := utils.ParsePublicKey([]byte(publicKey)); err != nil {
+			return nil, errors.NewError(errors.ErrorTypeValidation, "invalid public key", err)
+		}
+	}
+
+	client := &model.OAuthClient{
+		ClientID:               uuid.New().String(),
+		Name:                   name,
+		PublicKey:              publicKey,
+		RedirectURIs:           []string{},
+		PostLogoutRedirectURIs: []string{},
+		GrantTypes:             []string{GrantClientCredentials},
+		Scopes:                 grant.Scopes,
+		Audiences:              grant.Audiences,
+		CreatedAt:              time.Now(),
+	}
+
+	var secret string
+	if publicKey == "" {
+		var err error
+		secret, client.ClientSecretHash, err = s.generateClientSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ClientRepo.Create(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return newRegisteredClient(client, secret), nil
+}
+
+func (s *OAuthService) RotateClientSecret(ctx context.Context, clientID string) (string, error) {
+
+	secret, secretHash, err := s.generateClientSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.ClientRepo.UpdateSecretHash(ctx, clientID, secretHash); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+func (s *OAuthService) generateClientSecret() (string, string, error) {
+
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", errors.NewError(errors.ErrorTypeInternal, "failed generate client secret", err)
+	}
+
+	secretHash, err := s.Tokens.HashClientSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, secretHash, nil
+}
+
+func invalidGrant(err error) error {
+	if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeAuth {
+		return oauthError(errors.ErrorTypeAuth, OAuthInvalidGrant, appErr.Message, err)
+	}
+	return err
+}
+
+func (s *OAuthService) newTokenResponse(issued *IssuedSession) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:  issued.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.Tokens.AccessTokenTTL.Seconds()),
+		RefreshToken: issued.RefreshToken,
+		Scope:        issued.Scope,
+	}
+}
+
+// Logout accepts postLogoutRedirectURI only when it is registered for the
+// client the ID token was issued to.
+func (s *OAuthService) Logout(ctx context.Context, idTokenHint, clientID, postLogoutRedirectURI string) error {
+
+	if idTokenHint == "" {
+		return errors.NewError(errors.ErrorTypeValidation, "id_token_hint is required", nil)
+	}
+
+	claims, err := s.Tokens.ParseIDTokenHint(idTokenHint)
+	if err != nil {
+		return err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != s.Tokens.Issuer {
+		return errors.NewError(errors.ErrorTypeAuth, "ID token was issued by another issuer", nil)
+	}
+
+	audience, _ := claims["aud"].(string)
+	if clientID != "" && clientID != audience {
+		return errors.NewError(errors.ErrorTypeValidation, "client_id does not match the ID token", nil)
+	}
+
+	if postLogoutRedirectURI != "" {
+		client, err := s.ClientRepo.GetByID(ctx, audience)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(client.PostLogoutRedirectURIs, postLogoutRedirectURI) {
+			return errors.NewError(errors.ErrorTypeValidation, "post_logout_redirect_uri is not registered for the client", nil)
+		}
+	}
+
+	sessionID, ok := claims["sid"].(string)
+	if !ok {
+		return errors.NewError(errors.ErrorTypeAuth, "invalid session ID in ID token", nil)
+	}
+
+	return s.AuthService.EndClientSession(ctx, audience, sessionID)
+}
+
+// Introspect is only allowed for confidential clients.
+func (s *OAuthService) Introspect(ctx context.Context, credentials ClientCredentials, token, tokenTypeHint string) (*TokenInfo, error) {
+
+	client, err := s.authenticateClient(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+	if client.ClientSecretHash == "" && client.PublicKey == "" {
+		return nil, oauthError(errors.ErrorTypeAuth, OAuthInvalidClient, "public clients may not introspect tokens", nil)
+	}
+
+	if token == "" {
+		return nil, oauthError(errors.ErrorTypeValidation, OAuthInvalidRequest, "token is required", nil)
+	}
+
+	introspectors := []func(context.Context, string) (*TokenInfo, error){
+		s.AuthService.IntrospectAccessToken,
+		s.AuthService.IntrospectRefreshToken,
+	}
+	if tokenTypeHint == TokenTypeRefresh {
+		slices.Reverse(introspectors)
+	}
+
+	for _, introspect := range introspectors {
+		info, err := introspect(ctx, token)
+		if err != nil {
+			return nil, oauthError(errors.ErrorTypeInternal, OAuthServerError, "failed introspect token", err)
+		}
+		if info.Active {
+			return info, nil
+		}
+	}
+
+	return &TokenInfo{Active: false}, nil
+}
+
+// Revoke accepts only first-party tokens without client credentials and only
+// tokens issued to the authenticated client with them.
+func (s *OAuthService) Revoke(ctx context.Context, credentials ClientCredentials, token, tokenTypeHint string) error {
+
+	var clientID string
+	if credentials != (ClientCredentials{}) {
+		client, err := s.authenticateClient(ctx, credentials)
+		if err != nil {
+			return err
+		}
+		clientID = client.ClientID
+	}
+
+	if token == "" {
+		return oauthError(errors.ErrorTypeValidation, OAuthInvalidRequest, "token is required", nil)
+	}
+
+	err := s.AuthService.RevokeToken(ctx, clientID, token, tokenTypeHint)
+	if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeAuth {
+		return oauthError(errors.ErrorTypeAuth, OAuthUnauthorizedClient, appErr.Message, err)
+	}
+	return err
+}
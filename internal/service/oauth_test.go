@@ -0,0 +1,231 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+type fakeOAuthClientRepository struct {
+	clients  map[string]*model.OAuthClient
+	consents map[string]*model.OAuthConsent
+}
+
+func (r *fakeOAuthClientRepository) Create(ctx context.Context, client *model.OAuthClient) error {
+	r.clients[client.ClientID] = client
+	return nil
+}
+
+func (r *fakeOAuthClientRepository) GetByID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	client, ok := r.clients[clientID]
+	if !ok {
+		return nil, errors.NewError(errors.ErrorTypeNotFound, "client not found", nil)
+	}
+	return client, nil
+}
+
+func (r *fakeOAuthClientRepository) UpdateSecretHash(ctx context.Context, clientID, secretHash string) error {
+	r.clients[clientID].ClientSecretHash = secretHash
+	return nil
+}
+
+func (r *fakeOAuthClientRepository) GetConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error) {
+	consent, ok := r.consents[userID.String()+" "+clientID]
+	if !ok {
+		return nil, errors.NewError(errors.ErrorTypeNotFound, "consent not found", nil)
+	}
+	return consent, nil
+}
+
+func (r *fakeOAuthClientRepository) SaveConsent(ctx context.Context, consent *model.OAuthConsent) error {
+	r.consents[consent.UserID.String()+" "+consent.ClientID] = consent
+	return nil
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// newTestOAuthService returns the service with two public clients, "app" and
+// "other", and the authorization codes in miniredis.
+func newTestOAuthService(t *testing.T) (*OAuthService, *AuthService) {
+	t.Helper()
+
+	authService, _ := newTestAuthService(t)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	clientRepo := &fakeOAuthClientRepository{clients: map[string]*model.OAuthClient{}, consents: map[string]*model.OAuthConsent{}}
+	for _, clientID := range []string{"app", "other"} {
+		clientRepo.Create(context.Background(), &model.OAuthClient{
+			ClientID:     clientID,
+			RedirectURIs: []string{testRedirectURI},
+			GrantTypes:   []string{GrantAuthorizationCode, GrantRefreshToken},
+			Scopes:       []string{ScopeOpenID, ScopeEmail},
+		})
+	}
+
+	return NewOAuthService(authService.Tokens, authService, clientRepo, NewRedisChallengeStore(redisClient)), authService
+}
+
+// issueAuthorizationCode logs a user in, records the consent and issues a code
+// for the "app" client.
+func issueAuthorizationCode(t *testing.T, s *OAuthService) string {
+	t.Helper()
+
+	ctx := sessionContext(testUserAgent)
+	userID := uuid.New()
+	accessToken, _, err := s.AuthService.NewSession(ctx, userID, []string{AMRPassword})
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	claims, err := s.AuthService.VerifyAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken: %v", err)
+	}
+
+	client, err := s.AuthorizeClient(ctx, "app", testRedirectURI)
+	if err != nil {
+		t.Fatalf("AuthorizeClient: %v", err)
+	}
+	req := AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "app",
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid email",
+		CodeChallenge:       pkceChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	}
+	if err := s.GrantConsent(ctx, claims, client, req, true); err != nil {
+		t.Fatalf("GrantConsent: %v", err)
+	}
+
+	code, err := s.Authorize(ctx, claims, client, req)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return code
+}
+
+func TestExchangeCode(t *testing.T) {
+
+	tests := []struct {
+		name string
+		// modify changes the valid token request for the code.
+		modify   func(req *TokenRequest)
+		reuse    bool
+		wantCode string
+	}{
+		{
+			name:   "valid verifier",
+			modify: func(req *TokenRequest) {},
+		},
+		{
+			name:     "wrong verifier",
+			modify:   func(req *TokenRequest) { req.CodeVerifier = strings.Repeat("a", 43) },
+			wantCode: OAuthInvalidGrant,
+		},
+		{
+			name:     "missing verifier",
+			modify:   func(req *TokenRequest) { req.CodeVerifier = "" },
+			wantCode: OAuthInvalidRequest,
+		},
+		{
+			name:     "other redirect URI",
+			modify:   func(req *TokenRequest) { req.RedirectURI = "https://app.example.com/other" },
+			wantCode: OAuthInvalidGrant,
+		},
+		{
+			name:     "other client",
+			modify:   func(req *TokenRequest) { req.ClientID = "other" },
+			wantCode: OAuthInvalidGrant,
+		},
+		{
+			name:     "reused code",
+			modify:   func(req *TokenRequest) {},
+			reuse:    true,
+			wantCode: OAuthInvalidGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, authService := newTestOAuthService(t)
+			ctx := sessionContext(testUserAgent)
+
+			req := TokenRequest{
+				ClientCredentials: ClientCredentials{ClientID: "app"},
+				GrantType:         GrantAuthorizationCode,
+				Code:              issueAuthorizationCode(t, s),
+				RedirectURI:       testRedirectURI,
+				CodeVerifier:      testCodeVerifier,
+			}
+			if tt.reuse {
+				if _, err := s.Token(ctx, req); err != nil {
+					t.Fatalf("first Token: %v", err)
+				}
+			}
+			tt.modify(&req)
+
+			response, err := s.Token(ctx, req)
+			if tt.wantCode != "" {
+				appErr, ok := errors.IsAppError(err)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("Token error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Token: %v", err)
+			}
+
+			if response.IDToken == "" {
+				t.Error("missing ID token for the openid scope")
+			}
+			claims, err := authService.VerifyAccessTokenFor(response.AccessToken, authService.Tokens.Audience)
+			if err != nil {
+				t.Fatalf("issued access token: %v", err)
+			}
+			if claims.ClientID != "app" {
+				t.Errorf("access token client = %q, want app", claims.ClientID)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRequiresS256(t *testing.T) {
+
+	s, _ := newTestOAuthService(t)
+	client, err := s.AuthorizeClient(context.Background(), "app", testRedirectURI)
+	if err != nil {
+		t.Fatalf("AuthorizeClient: %v", err)
+	}
+
+	for _, method := range []string{"", "plain"} {
+		_, err := s.Authorize(context.Background(), nil, client, AuthorizeRequest{
+			ResponseType:        "code",
+			RedirectURI:         testRedirectURI,
+			CodeChallenge:       testCodeVerifier,
+			CodeChallengeMethod: method,
+		})
+		if appErr, ok := errors.IsAppError(err); !ok || appErr.Code != OAuthInvalidRequest {
+			t.Errorf("Authorize with method %q error = %v, want %s", method, err, OAuthInvalidRequest)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+	RoleUser    = "user"
+)
+
+const (
+	PermissionKeysRotate     = "keys:rotate"
+	PermissionClientsManage  = "clients:manage"
+	PermissionRolesManage    = "roles:manage"
+	PermissionSessionsRead   = "sessions:read"
+	PermissionSessionsRevoke = "sessions:revoke"
+)
+
+// Role permissions are looked up on every check, so changes to a role apply
+// immediately; changes to a user's roles reach tokens with the next refresh.
+type RBACService struct {
+	RoleRepo repository.IRoleRepository
+}
+
+func NewRBACService(roleRepo repository.IRoleRepository) *RBACService {
+	return &RBACService{
+		RoleRepo: roleRepo,
+	}
+}
+
+func (s *RBACService) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	permissions, err := s.RoleRepo.GetPermissions(ctx, roles)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(permissions, permission), nil
+}
+
+func (s *RBACService) ListRoles(ctx context.Context) ([]model.Role, error) {
+	return s.RoleRepo.ListRoles(ctx)
+}
+
+func (s *RBACService) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.RoleRepo.GetUserRoles(ctx, userID)
+}
+
+func (s *RBACService) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if role == "" {
+		return errors.NewError(errors.ErrorTypeValidation, "role is required", nil)
+	}
+	return s.RoleRepo.AssignRole(ctx, userID, role)
+}
+
+func (s *RBACService) RemoveRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return s.RoleRepo.RemoveRole(ctx, userID, role)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionRoles(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		clientID  string
+		wantRoles []string
+	}{
+		{name: "first-party session", wantRoles: []string{RoleAdmin}},
+		{name: "OAuth client session", clientID: "app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, _ := newTestAuthService(t)
+			userID := uuid.New()
+			s.RoleRepo.AssignRole(context.Background(), userID, RoleAdmin)
+
+			issued, err := s.NewClientSession(sessionContext(testUserAgent), userID, SessionGrant{ClientID: tt.clientID, Scope: FirstPartyScope})
+			if err != nil {
+				t.Fatalf("NewClientSession: %v", err)
+			}
+			claims, err := s.VerifyAccessToken(issued.AccessToken)
+			if err != nil {
+				t.Fatalf("VerifyAccessToken: %v", err)
+			}
+			if !slices.Equal(claims.Roles, tt.wantRoles) {
+				t.Errorf("roles = %v, want %v", claims.Roles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+
+	s := NewRBACService(&fakeRoleRepository{rolePermissions: map[string][]string{
+		RoleAdmin:   {PermissionRolesManage, PermissionSessionsRevoke},
+		RoleSupport: {PermissionSessionsRead},
+	}})
+
+	tests := []struct {
+		name       string
+		roles      []string
+		permission string
+		want       bool
+	}{
+		{name: "granted", roles: []string{RoleAdmin}, permission: PermissionRolesManage, want: true},
+		{name: "granted by second role", roles: []string{RoleSupport, RoleAdmin}, permission: PermissionSessionsRevoke, want: true},
+		{name: "not granted", roles: []string{RoleSupport}, permission: PermissionSessionsRevoke},
+		{name: "no roles", permission: PermissionSessionsRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.HasPermission(context.Background(), tt.roles, tt.permission)
+			if err != nil {
+				t.Fatalf("HasPermission: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HasPermission = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRevokeToken(t *testing.T) {
+
+	tests := []struct {
+		name string
+		// clientID is the session owner, revoker the authenticated caller.
+		clientID        string
+		revoker         string
+		refresh         bool
+		hint            string
+		wantErr         bool
+		wantRevoked     bool
+		wantBlacklisted bool
+	}{
+		{name: "first-party refresh token", refresh: true, wantRevoked: true, wantBlacklisted: true},
+		{name: "first-party access token", wantBlacklisted: true},
+		{name: "refresh token with access hint", refresh: true, hint: TokenTypeAccess, wantRevoked: true, wantBlacklisted: true},
+		{name: "access token with refresh hint", hint: TokenTypeRefresh, wantBlacklisted: true},
+		{name: "client refresh token", clientID: "app", revoker: "app", refresh: true, wantRevoked: true, wantBlacklisted: true},
+		{name: "client access token", clientID: "app", revoker: "app", wantBlacklisted: true},
+		{name: "client refresh token without client authentication", clientID: "app", refresh: true, wantErr: true},
+		{name: "client refresh token of another client", clientID: "app", revoker: "other", refresh: true, wantErr: true},
+		{name: "client access token of another client", clientID: "app", revoker: "other", wantErr: true},
+		{name: "first-party token revoked by a client", revoker: "app", refresh: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, tokenRepo := newTestAuthService(t)
+			ctx := sessionContext(testUserAgent)
+
+			issued, err := s.NewClientSession(ctx, uuid.New(), SessionGrant{ClientID: tt.clientID, Scope: FirstPartyScope})
+			if err != nil {
+				t.Fatalf("NewClientSession: %v", err)
+			}
+			token := issued.AccessToken
+			if tt.refresh {
+				token = issued.RefreshToken
+			}
+
+			err = s.RevokeToken(context.Background(), tt.revoker, token, tt.hint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RevokeToken error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if revoked := tokenRepo.sessions[issued.SessionID].Revoked; revoked != tt.wantRevoked {
+				t.Errorf("session revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+			if blacklisted := isBlacklisted(t, s, issued.SessionID); blacklisted != tt.wantBlacklisted {
+				t.Errorf("access token blacklisted = %v, want %v", blacklisted, tt.wantBlacklisted)
+			}
+		})
+	}
+}
+
+func TestRevokeTokenIgnoresUnknownTokens(t *testing.T) {
+
+	s, _ := newTestAuthService(t)
+
+	issued, err := s.NewClientSession(sessionContext(testUserAgent), uuid.New(), SessionGrant{Scope: FirstPartyScope})
+	if err != nil {
+		t.Fatalf("NewClientSession: %v", err)
+	}
+	if err := s.RevokeToken(context.Background(), "", issued.RefreshToken, ""); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+
+	for _, token := range []string{"", "not-a-token", issued.RefreshToken} {
+		if err := s.RevokeToken(context.Background(), "", token, TokenTypeRefresh); err != nil {
+			t.Errorf("RevokeToken(%q) = %v, want nil", token, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"authservice/internal/utils"
+	"context"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const minPasswordLength = 8
+
+// dummyPasswordHash is checked when the user does not exist, so that a login
+// with an unknown email takes as long as one with a wrong password.
+var dummyPasswordHash, _ = utils.HashPassword("dummy-password")
+
+type UserService struct {
+	UserRepo repository.IUserRepository
+}
+
+func NewUserService(userRepo repository.IUserRepository) *UserService {
+	return &UserService{
+		UserRepo: userRepo,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *UserService) GetUser(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	return s.UserRepo.GetByID(ctx, userID)
+}
+
+func (s *UserService) Register(ctx context.Context, email, password string) (*model.User, error) {
+
+	// ParseAddress also accepts display names and comments, as in
+	// "Name <a@b.c>", so only a bare address is allowed.
+	email = normalizeEmail(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "invalid email address", err)
+	}
+	if addr.Address != email {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "invalid email address", nil)
+	}
+
+	if len(password) < minPasswordLength {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "password must be at least 8 characters long", nil)
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed hash password", err)
+	}
+
+	now := time.Now()
+	user := &model.User{
+		ID:           uuid.New(),
+		Email:        email,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.UserRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*model.User, error) {
+
+	user, err := s.UserRepo.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.ErrorTypeNotFound {
+			utils.CheckPassword(password, dummyPasswordHash)
+			return nil, errors.NewError(errors.ErrorTypeAuth, "invalid email or password", nil)
+		}
+		return nil, err
+	}
+
+	ok, needsRehash := utils.CheckPassword(password, user.PasswordHash)
+	if !ok {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid email or password", nil)
+	}
+
+	if needsRehash {
+		passwordHash, err := utils.HashPassword(password)
+		if err == nil {
+			err = s.UserRepo.UpdatePasswordHash(ctx, user.ID, passwordHash)
+		}
+		if err != nil {
+			slog.Error("Failed to upgrade password hash", "user_id", user.ID, "error", err)
+		}
+	}
+
+	return user, nil
+}
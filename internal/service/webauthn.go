@@ -0,0 +1,271 @@
+package service
+
+import (
+	"authservice/internal/errors"
+	"authservice/internal/model"
+	"authservice/internal/repository"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const ceremonyTTL = 5 * time.Minute
+
+const (
+	AMRHardwareKey  = "hwk"
+	AMRUserPresence = "user"
+)
+
+type WebAuthnCeremony struct {
+	CeremonyID string `json:"ceremony_id"`
+	Options    any    `json:"options"`
+}
+
+type webAuthnUser struct {
+	user        *model.User
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+type WebAuthnService struct {
+	WebAuthn       *webauthn.WebAuthn
+	CredentialRepo repository.IWebAuthnRepository
+	UserRepo       repository.IUserRepository
+	Challenges     ChallengeStore
+}
+
+func NewWebAuthnService(
+	web *webauthn.WebAuthn,
+	credentialRepo repository.IWebAuthnRepository,
+	userRepo repository.IUserRepository,
+	challenges ChallengeStore,
+) *WebAuthnService {
+	return &WebAuthnService{
+		WebAuthn:       web,
+		CredentialRepo: credentialRepo,
+		UserRepo:       userRepo,
+		Challenges:     challenges,
+	}
+}
+
+func (s *WebAuthnService) loadUser(ctx context.Context, userID uuid.UUID) (*webAuthnUser, error) {
+
+	user, err := s.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.CredentialRepo.GetUserCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, credential := range stored {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(credential.Transports))
+		for _, transport := range credential.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credential.ID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(credential.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       credential.AAGUID,
+				SignCount:    uint32(credential.SignCount),
+				CloneWarning: credential.CloneWarning,
+			},
+		})
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+func (s *WebAuthnService) saveCeremony(ctx context.Context, kind string, session *webauthn.SessionData) (string, error) {
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", errors.NewError(errors.ErrorTypeInternal, "failed marshal WebAuthn session", err)
+	}
+
+	ceremonyID := uuid.New().String()
+	if err := s.Challenges.Save(ctx, kind+":"+ceremonyID, data, ceremonyTTL); err != nil {
+		return "", errors.NewError(errors.ErrorTypeRedis, "failed store WebAuthn challenge", err)
+	}
+
+	return ceremonyID, nil
+}
+
+func (s *WebAuthnService) takeCeremony(ctx context.Context, kind, ceremonyID string) (*webauthn.SessionData, error) {
+
+	data, err := s.Challenges.Take(ctx, kind+":"+ceremonyID)
+	if err != nil {
+		if stdErrors.Is(err, redis.Nil) {
+			return nil, errors.NewError(errors.ErrorTypeAuth, "WebAuthn ceremony not found or expired", nil)
+		}
+		return nil, errors.NewError(errors.ErrorTypeRedis, "failed load WebAuthn challenge", err)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed unmarshal WebAuthn session", err)
+	}
+
+	return &session, nil
+}
+
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, userID uuid.UUID) (*WebAuthnCeremony, error) {
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, session, err := s.WebAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed begin WebAuthn registration", err)
+	}
+
+	ceremonyID, err := s.saveCeremony(ctx, "reg", session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnCeremony{CeremonyID: ceremonyID, Options: options}, nil
+}
+
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, userID uuid.UUID, ceremonyID string, body io.Reader) ([]byte, error) {
+
+	session, err := s.takeCeremony(ctx, "reg", ceremonyID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "invalid WebAuthn registration response", err)
+	}
+
+	credential, err := s.WebAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "WebAuthn registration failed", err)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	err = s.CredentialRepo.Create(ctx, &model.WebAuthnCredential{
+		ID:              credential.ID,
+		UserID:          userID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		Flags:           int16(credential.Flags.ProtocolValue()),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		CreatedAt:       time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return credential.ID, nil
+}
+
+func (s *WebAuthnService) BeginLogin(ctx context.Context) (*WebAuthnCeremony, error) {
+
+	options, session, err := s.WebAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed begin WebAuthn login", err)
+	}
+
+	ceremonyID, err := s.saveCeremony(ctx, "login", session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnCeremony{CeremonyID: ceremonyID, Options: options}, nil
+}
+
+// FinishLogin rejects credentials whose signature counter went backwards,
+// since the authenticator was probably cloned.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, ceremonyID string, body io.Reader) (uuid.UUID, []string, error) {
+
+	session, err := s.takeCeremony(ctx, "login", ceremonyID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeValidation, "invalid WebAuthn login response", err)
+	}
+
+	lookup := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		return s.loadUser(ctx, userID)
+	}
+
+	user, credential, err := s.WebAuthn.ValidatePasskeyLogin(lookup, *session, parsed)
+	if err != nil {
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "WebAuthn login failed", err)
+	}
+	userID := user.(*webAuthnUser).user.ID
+
+	cloneWarning := credential.Authenticator.CloneWarning
+	err = s.CredentialRepo.UpdateSignCount(ctx, credential.ID, int64(credential.Authenticator.SignCount), cloneWarning)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	if cloneWarning {
+		slog.Warn("WebAuthn signature counter did not increase, authenticator may be cloned",
+			"user_id", userID, "sign_count", credential.Authenticator.SignCount)
+		return uuid.Nil, nil, errors.NewError(errors.ErrorTypeAuth, "WebAuthn credential may be cloned", nil)
+	}
+
+	amr := []string{AMRHardwareKey, AMRUserPresence}
+	if credential.Flags.UserVerified {
+		amr = append(amr, AMRMFA)
+	}
+
+	return userID, amr, nil
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"authservice/internal/model"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+type fakeUserRepository struct {
+	users map[uuid.UUID]*model.User
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	return r.users[userID], nil
+}
+
+func (r *fakeUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	r.users[userID].PasswordHash = passwordHash
+	return nil
+}
+
+type fakeWebAuthnRepository struct {
+	credentials []*model.WebAuthnCredential
+}
+
+func (r *fakeWebAuthnRepository) Create(ctx context.Context, credential *model.WebAuthnCredential) error {
+	r.credentials = append(r.credentials, credential)
+	return nil
+}
+
+func (r *fakeWebAuthnRepository) GetUserCredentials(ctx context.Context, userID uuid.UUID) ([]model.WebAuthnCredential, error) {
+	var credentials []model.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, *credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (r *fakeWebAuthnRepository) UpdateSignCount(ctx context.Context, credentialID []byte, signCount int64, cloneWarning bool) error {
+	for _, credential := range r.credentials {
+		if bytes.Equal(credential.ID, credentialID) {
+			credential.SignCount = signCount
+			credential.CloneWarning = cloneWarning
+		}
+	}
+	return nil
+}
+
+// softwareAuthenticator is a virtual passkey authenticator: it keeps one
+// resident ES256 credential, answers with "none" attestation and always
+// reports user presence and verification.
+type softwareAuthenticator struct {
+	origin       string
+	credentialID []byte
+	userHandle   []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T, origin string) *softwareAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate credential key: %v", err)
+	}
+
+	credentialID := make([]byte, 32)
+	rand.Read(credentialID)
+
+	return &softwareAuthenticator{
+		origin:       origin,
+		credentialID: credentialID,
+		key:          key,
+	}
+}
+
+func encodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (a *softwareAuthenticator) clientData(t *testing.T, ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	clientData, err := json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: encodeBase64(challenge),
+		Origin:    a.origin,
+	})
+	if err != nil {
+		t.Fatalf("marshal client data: %v", err)
+	}
+	return clientData
+}
+
+// authenticatorData builds the authenticator data; attestedCredential is
+// appended for registrations.
+func (a *softwareAuthenticator) authenticatorData(rpID string, flags protocol.AuthenticatorFlags, attestedCredential []byte) []byte {
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, byte(flags))
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attestedCredential...)
+}
+
+func (a *softwareAuthenticator) publicKey(t *testing.T) []byte {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("marshal COSE key: %v", err)
+	}
+	return publicKey
+}
+
+// create answers navigator.credentials.create with the response body the
+// browser would send.
+func (a *softwareAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+
+	a.userHandle = options.Response.User.ID.(protocol.URLEncodedBase64)
+
+	attestedCredential := make([]byte, 16) // zero AAGUID
+	attestedCredential = binary.BigEndian.AppendUint16(attestedCredential, uint16(len(a.credentialID)))
+	attestedCredential = append(attestedCredential, a.credentialID...)
+	attestedCredential = append(attestedCredential, a.publicKey(t)...)
+
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData
+	attestationObject, err := webauthncbor.Marshal(struct {
+		Format       string         `cbor:"fmt"`
+		AttStatement map[string]any `cbor:"attStmt"`
+		AuthData     []byte         `cbor:"authData"`
+	}{
+		Format:       "none",
+		AttStatement: map[string]any{},
+		AuthData:     a.authenticatorData(options.Response.RelyingParty.ID, flags, attestedCredential),
+	})
+	if err != nil {
+		t.Fatalf("marshal attestation object: %v", err)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"id":    encodeBase64(a.credentialID),
+		"rawId": encodeBase64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encodeBase64(a.clientData(t, protocol.CreateCeremony, options.Response.Challenge)),
+			"attestationObject": encodeBase64(attestationObject),
+			"transports":        []string{"internal"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal registration response: %v", err)
+	}
+	return body
+}
+
+// get answers navigator.credentials.get with the stored credential.
+func (a *softwareAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion) []byte {
+	t.Helper()
+
+	a.signCount++
+
+	clientData := a.clientData(t, protocol.AssertCeremony, options.Response.Challenge)
+	authData := a.authenticatorData(options.Response.RelyingPartyID, protocol.FlagUserPresent|protocol.FlagUserVerified, nil)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(slices.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"id":    encodeBase64(a.credentialID),
+		"rawId": encodeBase64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encodeBase64(clientData),
+			"authenticatorData": encodeBase64(authData),
+			"signature":         encodeBase64(signature),
+			"userHandle":        encodeBase64(a.userHandle),
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal assertion response: %v", err)
+	}
+	return body
+}
+
+// newTestWebAuthnService returns the service with one user, in-memory
+// credentials and the challenges in miniredis.
+func newTestWebAuthnService(t *testing.T) (*WebAuthnService, *fakeWebAuthnRepository, uuid.UUID) {
+	t.Helper()
+
+	web, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "Auth Service",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	user := &model.User{ID: uuid.New(), Email: "user@example.com"}
+	userRepo := &fakeUserRepository{users: map[uuid.UUID]*model.User{user.ID: user}}
+	credentialRepo := &fakeWebAuthnRepository{}
+
+	return NewWebAuthnService(web, credentialRepo, userRepo, NewRedisChallengeStore(redisClient)), credentialRepo, user.ID
+}
+
+func register(t *testing.T, s *WebAuthnService, userID uuid.UUID, authenticator *softwareAuthenticator) error {
+	t.Helper()
+
+	ceremony, err := s.BeginRegistration(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+
+	body := authenticator.create(t, ceremony.Options.(*protocol.CredentialCreation))
+	_, err = s.FinishRegistration(context.Background(), userID, ceremony.CeremonyID, bytes.NewReader(body))
+	return err
+}
+
+func login(t *testing.T, s *WebAuthnService, authenticator *softwareAuthenticator) (uuid.UUID, []string, error) {
+	t.Helper()
+
+	ceremony, err := s.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+
+	body := authenticator.get(t, ceremony.Options.(*protocol.CredentialAssertion))
+	return s.FinishLogin(context.Background(), ceremony.CeremonyID, bytes.NewReader(body))
+}
+
+func TestWebAuthnRegistrationAndLogin(t *testing.T) {
+
+	s, credentialRepo, userID := newTestWebAuthnService(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+
+	if err := register(t, s, userID, authenticator); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if len(credentialRepo.credentials) != 1 {
+		t.Fatalf("stored %d credentials, want 1", len(credentialRepo.credentials))
+	}
+	stored := credentialRepo.credentials[0]
+	if !bytes.Equal(stored.ID, authenticator.credentialID) || stored.UserID != userID {
+		t.Errorf("stored credential %x of user %s, want %x of user %s", stored.ID, stored.UserID, authenticator.credentialID, userID)
+	}
+
+	gotUserID, amr, err := login(t, s, authenticator)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if gotUserID != userID {
+		t.Errorf("FinishLogin user = %s, want %s", gotUserID, userID)
+	}
+	wantAMR := []string{AMRHardwareKey, AMRUserPresence, AMRMFA}
+	if !slices.Equal(amr, wantAMR) {
+		t.Errorf("FinishLogin amr = %v, want %v", amr, wantAMR)
+	}
+	if stored.SignCount != int64(authenticator.signCount) {
+		t.Errorf("stored sign count = %d, want %d", stored.SignCount, authenticator.signCount)
+	}
+}
+
+func TestWebAuthnRegistrationRejectsOtherOrigin(t *testing.T) {
+
+	s, credentialRepo, userID := newTestWebAuthnService(t)
+
+	if err := register(t, s, userID, newSoftwareAuthenticator(t, "https://evil.example.net")); err == nil {
+		t.Fatal("FinishRegistration succeeded for a foreign origin")
+	}
+	if len(credentialRepo.credentials) != 0 {
+		t.Errorf("stored %d credentials, want 0", len(credentialRepo.credentials))
+	}
+}
+
+func TestWebAuthnCeremonyIsSingleUse(t *testing.T) {
+
+	s, _, userID := newTestWebAuthnService(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	if err := register(t, s, userID, authenticator); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	ceremony, err := s.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	body := authenticator.get(t, ceremony.Options.(*protocol.CredentialAssertion))
+
+	if _, _, err := s.FinishLogin(context.Background(), ceremony.CeremonyID, bytes.NewReader(body)); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, _, err := s.FinishLogin(context.Background(), ceremony.CeremonyID, bytes.NewReader(body)); err == nil {
+		t.Fatal("replayed FinishLogin succeeded")
+	}
+}
+
+func TestWebAuthnLoginRejectsClonedAuthenticator(t *testing.T) {
+
+	s, credentialRepo, userID := newTestWebAuthnService(t)
+	authenticator := newSoftwareAuthenticator(t, testOrigin)
+	if err := register(t, s, userID, authenticator); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	if _, _, err := login(t, s, authenticator); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, _, err := login(t, s, authenticator); err != nil {
+		t.Fatalf("second FinishLogin: %v", err)
+	}
+
+	// A copy of the key still has the counter of the first login.
+	authenticator.signCount = 0
+	if _, _, err := login(t, s, authenticator); err == nil {
+		t.Fatal("FinishLogin succeeded with a signature counter that went backwards")
+	}
+	if !credentialRepo.credentials[0].CloneWarning {
+		t.Error("clone warning was not stored")
+	}
+}
@@ -1,28 +1,243 @@
 package utils
 
 import (
+	"authservice/internal/config"
 	"authservice/internal/errors"
-	"os"
+	"crypto/sha256"
+	"crypto/sha512"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func GenerateJWT(userID, sessionID string) (string, error) {
+const MFATokenTTL = 5 * time.Minute
 
-	secret := os.Getenv("ACCESS_SECRET")
-	if secret == "" {
-		return "", errors.NewError(errors.ErrorTypeInternal, "ACCESS_SECRET not configured", nil)
+// tokenUseMFA tokens only prove the first factor and are rejected by ParseToken.
+const tokenUseMFA = "mfa_pending"
+
+// tokenUseID marks OpenID Connect ID tokens, so they cannot be used as
+// access tokens.
+const tokenUseID = "id"
+
+type IDToken struct {
+	Issuer      string
+	UserID      string
+	ClientID    string
+	SessionID   string
+	Nonce       string
+	AuthTime    time.Time
+	AMR         []string
+	AccessToken string
+}
+
+type TokenIssuer struct {
+	Keyring            *Keyring
+	Issuer             string
+	Audience           string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	RefreshTokenPepper string
+}
+
+func NewTokenIssuer(cfg *config.Config, keyring *Keyring) *TokenIssuer {
+	return &TokenIssuer{
+		Keyring:            keyring,
+		Issuer:             cfg.OIDC.Issuer,
+		Audience:           cfg.JWT.Audience,
+		AccessTokenTTL:     cfg.JWT.AccessTokenTTL,
+		RefreshTokenTTL:    cfg.RefreshToken.TTL,
+		RefreshTokenPepper: cfg.RefreshToken.Pepper,
+	}
+}
+
+type AccessTokenClaims struct {
+	UserID    string
+	SessionID string
+	ClientID  string
+	AMR       []string
+	Scope     string
+	Audience  []string
+	Roles     []string
+	AuthTime  time.Time
+}
+
+func (t *TokenIssuer) GenerateJWT(accessToken AccessTokenClaims) (string, error) {
+
+	if len(accessToken.Audience) == 0 {
+		accessToken.Audience = []string{t.Audience}
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   t.Issuer,
+		"aud":   accessToken.Audience,
+		"exp":   now.Add(t.AccessTokenTTL).Unix(),
+		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+		"jti":   uuid.New().String(),
+		"scope": accessToken.Scope,
+	}
+
+	if accessToken.UserID != "" {
+		claims["sub"] = accessToken.UserID
+		claims["uid"] = accessToken.UserID
+		claims["sid"] = accessToken.SessionID
+		claims["amr"] = accessToken.AMR
+		if !accessToken.AuthTime.IsZero() {
+			claims["auth_time"] = accessToken.AuthTime.Unix()
+		}
+		if len(accessToken.Roles) > 0 {
+			claims["roles"] = accessToken.Roles
+		}
+	} else {
+		claims["sub"] = accessToken.ClientID
+	}
+	if accessToken.ClientID != "" {
+		claims["client_id"] = accessToken.ClientID
+	}
+
+	return t.signClaims(claims)
+}
+
+// ParseClientAssertion leaves the iss, sub, aud and jti checks to the caller.
+func ParseClientAssertion(assertion string, key *SigningKey) (jwt.MapClaims, error) {
+
+	token, err := jwt.Parse(assertion, func(token *jwt.Token) (interface{}, error) {
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{key.Method.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid client assertion", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid client assertion claims", nil)
+	}
+
+	return claims, nil
+}
+
+// UnverifiedSubject is only used to find the key the token is then verified
+// with.
+func UnverifiedSubject(strToken string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(strToken, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+	sub, _ := token.Claims.GetSubject()
+	return sub
+}
+
+func (t *TokenIssuer) GenerateMFAToken(userID string) (string, error) {
+
+	claims := jwt.MapClaims{
+		"uid":       userID,
+		"jti":       uuid.NewString(),
+		"exp":       time.Now().Add(MFATokenTTL).Unix(),
+		"token_use": tokenUseMFA,
+	}
+
+	return t.signClaims(claims)
+}
+
+func (t *TokenIssuer) ParseMFAToken(strToken string) (string, string, error) {
+
+	token, err := jwt.Parse(strToken, t.lookupVerificationKey)
+	if err != nil {
+		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid MFA token", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["token_use"] != tokenUseMFA {
+		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid MFA token claims", nil)
+	}
+
+	userID, ok := claims["uid"].(string)
+	if !ok {
+		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid user ID in MFA token", nil)
 	}
 
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return "", "", errors.NewError(errors.ErrorTypeAuth, "invalid token ID in MFA token", nil)
+	}
+
+	return userID, tokenID, nil
+}
+
+func (t *TokenIssuer) GenerateIDToken(idToken IDToken) (string, error) {
+
+	key := t.Keyring.Active()
+
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"uid": userID,
-		"exp": time.Now().Add(30 * time.Minute).Unix(),
-		"sid": sessionID,
+		"iss":       idToken.Issuer,
+		"sub":       idToken.UserID,
+		"aud":       idToken.ClientID,
+		"exp":       now.Add(t.AccessTokenTTL).Unix(),
+		"iat":       now.Unix(),
+		"auth_time": idToken.AuthTime.Unix(),
+		"sid":       idToken.SessionID,
+		"amr":       idToken.AMR,
+		"token_use": tokenUseID,
+	}
+	if idToken.Nonce != "" {
+		claims["nonce"] = idToken.Nonce
 	}
+	if idToken.AccessToken != "" {
+		claims["at_hash"] = tokenHashClaim(key.Method, idToken.AccessToken)
+	}
+
+	return signClaimsWithKey(key, claims)
+}
+
+// tokenHashClaim computes at_hash (OpenID Connect Core section 3.1.3.6).
+func tokenHashClaim(method jwt.SigningMethod, token string) string {
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	signedToken, err := token.SignedString([]byte(secret))
+	var sum []byte
+	switch method.Alg() {
+	case "HS384", "RS384", "ES384":
+		digest := sha512.Sum384([]byte(token))
+		sum = digest[:]
+	case "HS512", "RS512", "ES512", "EdDSA":
+		digest := sha512.Sum512([]byte(token))
+		sum = digest[:]
+	default:
+		digest := sha256.Sum256([]byte(token))
+		sum = digest[:]
+	}
+
+	return encodeBase64URL(sum[:len(sum)/2])
+}
+
+// ParseIDTokenHint accepts hints expired for up to the refresh token TTL;
+// older hints are rejected, since a link with one would log the user out
+// without confirmation.
+func (t *TokenIssuer) ParseIDTokenHint(strToken string) (jwt.MapClaims, error) {
+
+	token, err := jwt.Parse(strToken, t.lookupVerificationKey, jwt.WithExpirationRequired(), jwt.WithLeeway(t.RefreshTokenTTL))
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid ID token", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["token_use"] != tokenUseID {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid ID token claims", nil)
+	}
+
+	return claims, nil
+}
+
+func (t *TokenIssuer) signClaims(claims jwt.MapClaims) (string, error) {
+	return signClaimsWithKey(t.Keyring.Active(), claims)
+}
+
+func signClaimsWithKey(key *SigningKey, claims jwt.MapClaims) (string, error) {
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signedToken, err := token.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", errors.NewError(errors.ErrorTypeInternal, "failed sign JWT token", err)
 	}
@@ -30,47 +245,81 @@ func GenerateJWT(userID, sessionID string) (string, error) {
 	return signedToken, nil
 }
 
-func ParseToken(strToken string) (jwt.MapClaims, error) {
+var ErrUnknownSigningKey = errors.NewError(errors.ErrorTypeAuth, "unknown signing key", nil)
 
-	secret := os.Getenv("ACCESS_SECRET")
-	if secret == "" {
-		return nil, errors.NewError(errors.ErrorTypeInternal, "ACCESS_SECRET not configured", nil)
-	}
+// lookupVerificationKey rejects tokens whose algorithm does not match the key
+// named by their kid header.
+func (t *TokenIssuer) lookupVerificationKey(token *jwt.Token) (interface{}, error) {
 
-	token, err := jwt.Parse(strToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.NewError(errors.ErrorTypeAuth, "unexpected signing method", nil)
+	key := t.Keyring.Active()
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok = t.Keyring.Lookup(kid)
+		if !ok {
+			return nil, ErrUnknownSigningKey
 		}
-		return []byte(secret), nil
-	})
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "unexpected signing method", nil)
+	}
+
+	return key.PublicKey, nil
+}
+
+func (t *TokenIssuer) ParseToken(strToken string) (jwt.MapClaims, error) {
+	return t.ParseAccessToken(strToken, t.Audience)
+}
+
+// ParseAccessToken accepts tokens for any audience when audience is empty.
+func (t *TokenIssuer) ParseAccessToken(strToken, audience string) (jwt.MapClaims, error) {
+
+	options := []jwt.ParserOption{jwt.WithIssuedAt()}
+	if t.Issuer != "" {
+		options = append(options, jwt.WithIssuer(t.Issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+
+	token, err := jwt.Parse(strToken, t.lookupVerificationKey, options...)
 	if err != nil {
 		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid token", err)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims["token_use"] != nil {
 		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid token claims", nil)
 	}
 
 	return claims, nil
 }
 
-func GetJWTTTL(tokenString string) (time.Duration, error) {
+// ParseExpiredToken only identifies the session, so issuer and audience are
+// not checked.
+func (t *TokenIssuer) ParseExpiredToken(strToken string, grace time.Duration) (jwt.MapClaims, error) {
 
-	secret := os.Getenv("ACCESS_SECRET")
-	if secret == "" {
-		return 0, errors.NewError(errors.ErrorTypeInternal, "ACCESS_SECRET not configured", nil)
+	token, err := jwt.Parse(strToken, t.lookupVerificationKey, jwt.WithLeeway(grace))
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid token", err)
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["token_use"] != nil {
+		return nil, errors.NewError(errors.ErrorTypeAuth, "invalid token claims", nil)
+	}
+
+	return claims, nil
+}
+
+func (t *TokenIssuer) GetJWTTTL(tokenString string) (time.Duration, error) {
+
+	token, err := jwt.Parse(tokenString, t.lookupVerificationKey)
 	if err != nil {
 		return 0, errors.NewError(errors.ErrorTypeAuth, "failed parse token for TTL calculation", err)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims["token_use"] != nil {
 		return 0, errors.NewError(errors.ErrorTypeAuth, "invalid token claims for TTL calculation", nil)
 	}
 
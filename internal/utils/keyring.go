@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"authservice/internal/errors"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type verificationKey struct {
+	key      *SigningKey
+	retireAt time.Time
+}
+
+type Keyring struct {
+	mu      sync.RWMutex
+	active  *SigningKey
+	retired []verificationKey
+}
+
+func NewKeyring(active *SigningKey, retired ...*SigningKey) *Keyring {
+	keyring := &Keyring{
+		active: active,
+	}
+	for _, key := range retired {
+		keyring.retired = append(keyring.retired, verificationKey{key: key})
+	}
+	return keyring
+}
+
+func (k *Keyring) Active() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+func (k *Keyring) Lookup(keyID string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active.ID == keyID {
+		return k.active, true
+	}
+	now := time.Now()
+	for _, retired := range k.retired {
+		if retired.key.ID == keyID && (retired.retireAt.IsZero() || now.Before(retired.retireAt)) {
+			return retired.key, true
+		}
+	}
+	return nil, false
+}
+
+func (k *Keyring) Keys() []*SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := []*SigningKey{k.active}
+	now := time.Now()
+	for _, retired := range k.retired {
+		if retired.retireAt.IsZero() || now.Before(retired.retireAt) {
+			keys = append(keys, retired.key)
+		}
+	}
+	return keys
+}
+
+type RetiredKey struct {
+	Key      *SigningKey
+	RetireAt time.Time
+}
+
+func (k *Keyring) Replace(active *SigningKey, retired []RetiredKey) {
+
+	keys := make([]verificationKey, 0, len(retired))
+	for _, key := range retired {
+		keys = append(keys, verificationKey{key: key.Key, retireAt: key.RetireAt})
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.active = active
+	k.retired = keys
+}
+
+func GenerateSigningKey(method jwt.SigningMethod) (*SigningKey, error) {
+
+	var privateKey crypto.PrivateKey
+	var err error
+
+	switch method {
+	case jwt.SigningMethodHS256, jwt.SigningMethodHS384, jwt.SigningMethodHS512:
+		secret := make([]byte, 64)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "failed generate HMAC secret", err)
+		}
+		key := NewHMACSigningKey(uuid.New().String(), secret)
+		key.Method = method
+		return key, nil
+	case jwt.SigningMethodRS256, jwt.SigningMethodRS384, jwt.SigningMethodRS512:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodES384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case jwt.SigningMethodES512:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case jwt.SigningMethodEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.NewError(errors.ErrorTypeInternal, "unsupported signing method "+method.Alg(), nil)
+	}
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed generate signing key", err)
+	}
+
+	return NewSigningKey("", privateKey)
+}
+
+func (k *Keyring) SigningAlgorithm() string {
+	return k.Active().Method.Alg()
+}
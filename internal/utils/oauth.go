@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+func GenerateOpaqueToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// HashClientSecret uses a keyed hash instead of a password hash: client secrets
+// are random, and the token endpoint stays fast.
+func (t *TokenIssuer) HashClientSecret(secret string) (string, error) {
+	return t.keyedHash(secret)
+}
+
+func (t *TokenIssuer) CheckClientSecret(secret, secretHash string) bool {
+	hash, err := t.HashClientSecret(secret)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(hash), []byte(secretHash))
+}
+
+func ValidPKCEVerifier(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func VerifyPKCE(verifier, challenge string) bool {
+	if !ValidPKCEVerifier(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return hmac.Equal([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge))
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"net/netip"
+	"strings"
+)
+
+type TrustedProxies []netip.Prefix
+
+func NewTrustedProxies(cidrs []string) TrustedProxies {
+
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			proxies = append(proxies, prefix.Masked())
+		}
+	}
+
+	return proxies
+}
+
+func (p TrustedProxies) Trusts(remoteAddr string) bool {
+	addr, ok := parseRemoteAddr(remoteAddr)
+	return ok && p.contains(addr)
+}
+
+// ClientIP reads X-Forwarded-For from the right and only past trusted proxies,
+// since every entry left of the last proxy is set by the client.
+func (p TrustedProxies) ClientIP(remoteAddr, forwardedFor string) string {
+
+	addr, ok := parseRemoteAddr(remoteAddr)
+	if !ok {
+		return remoteAddr
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0 && p.contains(addr); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+
+	return addr.String()
+}
+
+func (p TrustedProxies) contains(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRemoteAddr(remoteAddr string) (netip.Addr, bool) {
+
+	if addrPort, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(remoteAddr); err == nil {
+		return addr.Unmap(), true
+	}
+
+	return netip.Addr{}, false
+}
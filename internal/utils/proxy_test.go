@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestClientIP(t *testing.T) {
+
+	proxies := NewTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32"})
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "direct client with forwarded header", remoteAddr: "203.0.113.7:5000", forwardedFor: "198.51.100.1", want: "203.0.113.7"},
+		{name: "behind proxy", remoteAddr: "10.0.0.2:5000", forwardedFor: "198.51.100.1", want: "198.51.100.1"},
+		{name: "spoofed entries before proxy", remoteAddr: "10.0.0.2:5000", forwardedFor: "192.0.2.66, 198.51.100.1", want: "198.51.100.1"},
+		{name: "proxy chain", remoteAddr: "10.0.0.2:5000", forwardedFor: "198.51.100.1, 10.0.0.3", want: "198.51.100.1"},
+		{name: "proxy without forwarded header", remoteAddr: "10.0.0.2:5000", want: "10.0.0.2"},
+		{name: "invalid forwarded entry", remoteAddr: "10.0.0.2:5000", forwardedFor: "unknown", want: "10.0.0.2"},
+		{name: "IPv6 proxy", remoteAddr: "[2001:db8::1]:5000", forwardedFor: "198.51.100.1", want: "198.51.100.1"},
+		{name: "IPv4-mapped proxy", remoteAddr: "[::ffff:10.0.0.2]:5000", forwardedFor: "198.51.100.1", want: "198.51.100.1"},
+		{name: "address without port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxies.ClientIP(tt.remoteAddr, tt.forwardedFor); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,58 @@
 package utils
 
 import (
+	"authservice/internal/errors"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-func GenerateRefreshToken() (string, string, error) {
+func (t *TokenIssuer) GenerateRefreshToken() (string, string, error) {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
 		return "", "", err
 	}
 
-	tokenStr := base64.StdEncoding.EncodeToString(tokenBytes)
-	tokenHash, err := bcrypt.GenerateFromPassword([]byte(tokenStr), bcrypt.DefaultCost)
+	tokenStr := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	tokenHash, err := t.HashRefreshToken(tokenStr)
 	if err != nil {
 		return "", "", err
 	}
-	return tokenStr, string(tokenHash), nil
+	return tokenStr, tokenHash, nil
 }
 
-func CheckRefreshToken(refreshToken string, refreshTokenHash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(refreshTokenHash), []byte(refreshToken))
-	return err == nil
+func (t *TokenIssuer) HashRefreshToken(refreshToken string) (string, error) {
+	return t.keyedHash(refreshToken)
+}
+
+func (t *TokenIssuer) keyedHash(value string) (string, error) {
+	if t.RefreshTokenPepper == "" {
+		return "", errors.NewError(errors.ErrorTypeInternal, "refresh token pepper not configured", nil)
+	}
+
+	mac := hmac.New(sha256.New, []byte(t.RefreshTokenPepper))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func IsLegacyRefreshTokenHash(refreshTokenHash string) bool {
+	return strings.HasPrefix(refreshTokenHash, "$2")
+}
+
+func (t *TokenIssuer) CheckRefreshToken(refreshToken string, refreshTokenHash string) bool {
+	if IsLegacyRefreshTokenHash(refreshTokenHash) {
+		err := bcrypt.CompareHashAndPassword([]byte(refreshTokenHash), []byte(refreshToken))
+		return err == nil
+	}
+
+	tokenHash, err := t.HashRefreshToken(refreshToken)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(tokenHash), []byte(refreshTokenHash))
 }
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"authservice/internal/config"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestTokenIssuer(pepper string) *TokenIssuer {
+	cfg := config.Default()
+	cfg.RefreshToken.Pepper = pepper
+	return NewTokenIssuer(cfg, NewKeyring(NewHMACSigningKey("test", []byte("test-secret"))))
+}
+
+func TestCheckRefreshToken(t *testing.T) {
+
+	tokens := newTestTokenIssuer("pepper")
+
+	refreshToken, keyedHash, err := tokens.GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken: %v", err)
+	}
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte(refreshToken), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		tokens       *TokenIssuer
+		refreshToken string
+		hash         string
+		want         bool
+	}{
+		{name: "keyed hash", tokens: tokens, refreshToken: refreshToken, hash: keyedHash, want: true},
+		{name: "keyed hash of another token", tokens: tokens, refreshToken: "other", hash: keyedHash},
+		{name: "keyed hash with another pepper", tokens: newTestTokenIssuer("other-pepper"), refreshToken: refreshToken, hash: keyedHash},
+		{name: "keyed hash without pepper", tokens: newTestTokenIssuer(""), refreshToken: refreshToken, hash: keyedHash},
+		{name: "bcrypt hash", tokens: tokens, refreshToken: refreshToken, hash: string(legacyHash), want: true},
+		{name: "bcrypt hash of another token", tokens: tokens, refreshToken: "other", hash: string(legacyHash)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tokens.CheckRefreshToken(tt.refreshToken, tt.hash); got != tt.want {
+				t.Errorf("CheckRefreshToken = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashRefreshTokenIsStable(t *testing.T) {
+
+	tokens := newTestTokenIssuer("pepper")
+
+	first, err := tokens.HashRefreshToken("token")
+	if err != nil {
+		t.Fatalf("HashRefreshToken: %v", err)
+	}
+	second, _ := tokens.HashRefreshToken("token")
+	if first != second {
+		t.Errorf("hashes differ: %s, %s", first, second)
+	}
+	if IsLegacyRefreshTokenHash(first) {
+		t.Errorf("keyed hash %s is reported as bcrypt", first)
+	}
+}
@@ -0,0 +1,344 @@
+package utils
+
+import (
+	"authservice/internal/config"
+	"authservice/internal/errors"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+func LoadKeyring(cfg config.JWTConfig) (*Keyring, error) {
+
+	active, err := loadActiveKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var retired []*SigningKey
+	for _, path := range cfg.VerificationKeyFiles {
+		key, err := LoadVerificationKey(path)
+		if err != nil {
+			return nil, err
+		}
+		retired = append(retired, key)
+	}
+
+	return NewKeyring(active, retired...), nil
+}
+
+func loadActiveKey(cfg config.JWTConfig) (*SigningKey, error) {
+
+	if cfg.SigningKeyFile != "" {
+		return LoadSigningKey(cfg.SigningKeyID, cfg.SigningKeyFile)
+	}
+
+	if cfg.Secret == "" {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "JWT signing key file or secret must be configured", nil)
+	}
+
+	keyID := cfg.SigningKeyID
+	if keyID == "" {
+		keyID = "default"
+	}
+
+	return NewHMACSigningKey(keyID, []byte(cfg.Secret)), nil
+}
+
+func NewHMACSigningKey(keyID string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:         keyID,
+		Method:     jwt.SigningMethodHS512,
+		PrivateKey: secret,
+		PublicKey:  secret,
+	}
+}
+
+func LoadSigningKey(keyID, path string) (*SigningKey, error) {
+
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningKey(keyID, privateKey)
+}
+
+func LoadVerificationKey(path string) (*SigningKey, error) {
+
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type != "PUBLIC KEY" {
+		privateKey, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		key, err := NewSigningKey("", privateKey)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = nil
+		return key, nil
+	}
+
+	return parsePublicKey(block)
+}
+
+func ParsePublicKey(data []byte) (*SigningKey, error) {
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.NewError(errors.ErrorTypeValidation, "public key must be a PEM encoded PUBLIC KEY block", nil)
+	}
+
+	return parsePublicKey(block)
+}
+
+func parsePublicKey(block *pem.Block) (*SigningKey, error) {
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed parse public key", err)
+	}
+
+	key := &SigningKey{
+		PublicKey: publicKey,
+	}
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		method, err := ecdsaSigningMethod(pub.Curve)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = method
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.NewError(errors.ErrorTypeInternal, "unsupported public key type", nil)
+	}
+
+	jwk, _ := key.PublicJWK()
+	key.ID, err = jwk.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed read key file", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "key file is not PEM encoded", nil)
+	}
+
+	return block, nil
+}
+
+func NewSigningKey(keyID string, privateKey crypto.PrivateKey) (*SigningKey, error) {
+
+	key := &SigningKey{
+		ID:         keyID,
+		PrivateKey: privateKey,
+	}
+
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.PublicKey = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		method, err := ecdsaSigningMethod(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = method
+		key.PublicKey = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.PublicKey = k.Public()
+	default:
+		return nil, errors.NewError(errors.ErrorTypeInternal, "unsupported signing key type", nil)
+	}
+
+	if key.ID == "" {
+		jwk, _ := key.PublicJWK()
+		thumbprint, err := jwk.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = thumbprint
+	}
+
+	return key, nil
+}
+
+func marshalPrivateKey(key *SigningKey) ([]byte, error) {
+
+	if key.Symmetric() {
+		secret, ok := key.PrivateKey.([]byte)
+		if !ok {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "invalid HMAC signing key", nil)
+		}
+		return secret, nil
+	}
+
+	data, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed marshal private key", err)
+	}
+	return data, nil
+}
+
+// storedKeyAAD binds a sealed key to its key ID and algorithm, so a sealed
+// key copied to another row does not decrypt.
+func storedKeyAAD(keyID, algorithm string) []byte {
+	return []byte(keyID + "\x00" + algorithm)
+}
+
+func keyEncryptionCipher(kek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "invalid key encryption key", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func SealPrivateKey(kek []byte, key *SigningKey) ([]byte, error) {
+
+	data, err := marshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := keyEncryptionCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed generate nonce", err)
+	}
+
+	return aead.Seal(nonce, nonce, data, storedKeyAAD(key.ID, key.Method.Alg())), nil
+}
+
+func ParseStoredKey(kek []byte, keyID, algorithm string, sealed []byte) (*SigningKey, error) {
+
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "unsupported signing method "+algorithm, nil)
+	}
+
+	aead, err := keyEncryptionCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "stored signing key "+keyID+" is not encrypted", nil)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, storedKeyAAD(keyID, algorithm))
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed decrypt stored signing key "+keyID, err)
+	}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		key := NewHMACSigningKey(keyID, data)
+		key.Method = method
+		return key, nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(data)
+	if err != nil {
+		return nil, errors.NewError(errors.ErrorTypeInternal, "failed parse stored private key", err)
+	}
+
+	key, err := NewSigningKey(keyID, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	// RSA keys can be used with several methods.
+	key.Method = method
+
+	return key, nil
+}
+
+// Symmetric reports whether the key is a shared HMAC secret, which must never
+// be published.
+func (k *SigningKey) Symmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+func ecdsaSigningMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	case elliptic.P521():
+		return jwt.SigningMethodES512, nil
+	default:
+		return nil, errors.NewError(errors.ErrorTypeInternal, "unsupported ECDSA curve", nil)
+	}
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.PrivateKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "failed parse RSA private key", err)
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "failed parse EC private key", err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.NewError(errors.ErrorTypeInternal, "failed parse PKCS#8 private key", err)
+		}
+		return key, nil
+	default:
+		return nil, errors.NewError(errors.ErrorTypeInternal, "unsupported PEM block type "+block.Type, nil)
+	}
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_sessions_user_id;
+
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS user_id;
\ No newline at end of file
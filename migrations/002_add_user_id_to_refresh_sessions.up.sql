@@ -0,0 +1,3 @@
+ALTER TABLE refresh_sessions ADD COLUMN user_id UUID;
+
+CREATE INDEX idx_refresh_sessions_user_id ON refresh_sessions (user_id) WHERE revoked = false;
\ No newline at end of file
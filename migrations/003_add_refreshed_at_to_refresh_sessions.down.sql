@@ -0,0 +1 @@
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS refreshed_at;
\ No newline at end of file
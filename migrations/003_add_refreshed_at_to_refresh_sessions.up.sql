@@ -0,0 +1,3 @@
+ALTER TABLE refresh_sessions ADD COLUMN refreshed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+UPDATE refresh_sessions SET refreshed_at = created_at;
\ No newline at end of file
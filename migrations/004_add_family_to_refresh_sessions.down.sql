@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_refresh_sessions_session_id;
+
+DROP INDEX IF EXISTS idx_refresh_sessions_family_id;
+
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS rotated;
+
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS family_id;
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE refresh_sessions ADD COLUMN family_id TEXT;
+
+UPDATE refresh_sessions SET family_id = session_id;
+
+ALTER TABLE refresh_sessions ALTER COLUMN family_id SET NOT NULL;
+
+ALTER TABLE refresh_sessions ADD COLUMN rotated BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_refresh_sessions_family_id ON refresh_sessions (family_id);
+
+CREATE INDEX idx_refresh_sessions_session_id ON refresh_sessions (session_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS amr;
+
+DROP TABLE IF EXISTS recovery_codes;
+
+DROP TABLE IF EXISTS user_totp;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE oauth_clients (
+    client_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    client_secret_hash TEXT,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    grant_types TEXT[] NOT NULL DEFAULT '{authorization_code,refresh_token}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE refresh_sessions ADD COLUMN client_id TEXT REFERENCES oauth_clients (client_id) ON DELETE CASCADE;
+
+CREATE INDEX idx_refresh_sessions_client_id ON refresh_sessions (client_id);
\ No newline at end of file
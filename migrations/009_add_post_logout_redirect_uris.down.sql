@@ -0,0 +1 @@
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS post_logout_redirect_uris;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE oauth_clients ADD COLUMN post_logout_redirect_uris TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
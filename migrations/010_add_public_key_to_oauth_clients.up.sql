@@ -0,0 +1 @@
+ALTER TABLE oauth_clients ADD COLUMN public_key TEXT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS audience;
+
+ALTER TABLE refresh_sessions DROP COLUMN IF EXISTS scope;
+
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS audiences;
+
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS scopes;
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE oauth_clients ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE oauth_clients ADD COLUMN audiences TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE oauth_clients SET scopes = '{openid,email}' WHERE 'authorization_code' = ANY (grant_types);
+
+ALTER TABLE refresh_sessions ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_sessions ADD COLUMN audience TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE refresh_sessions SET scope = 'openid email';
\ No newline at end of file
@@ -0,0 +1,47 @@
+CREATE TABLE roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+    role TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX idx_user_roles_role ON user_roles (role);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to the admin API'),
+    ('support', 'Inspects and revokes user sessions'),
+    ('user', 'Regular user');
+
+INSERT INTO permissions (name, description) VALUES
+    ('keys:rotate', 'Rotate the token signing key'),
+    ('clients:manage', 'Register OAuth clients and service accounts'),
+    ('roles:manage', 'Assign and remove user roles'),
+    ('sessions:read', 'Search and inspect user sessions'),
+    ('sessions:revoke', 'Revoke user sessions');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'keys:rotate'),
+    ('admin', 'clients:manage'),
+    ('admin', 'roles:manage'),
+    ('admin', 'sessions:read'),
+    ('admin', 'sessions:revoke'),
+    ('support', 'sessions:read'),
+    ('support', 'sessions:revoke');
+
+INSERT INTO user_roles (user_id, role) SELECT id, 'user' FROM users;
\ No newline at end of file
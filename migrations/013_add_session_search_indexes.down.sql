@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_sessions_user_id_refreshed_at;
+
+DROP INDEX IF EXISTS idx_refresh_sessions_ip_address;
\ No newline at end of file
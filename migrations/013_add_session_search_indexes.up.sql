@@ -0,0 +1,3 @@
+CREATE INDEX idx_refresh_sessions_ip_address ON refresh_sessions (ip_address);
+
+CREATE INDEX idx_refresh_sessions_user_id_refreshed_at ON refresh_sessions (user_id, refreshed_at DESC);
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE signing_keys (
+    kid TEXT PRIMARY KEY,
+    algorithm TEXT NOT NULL,
+    private_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    activate_at TIMESTAMPTZ NOT NULL,
+    retire_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_signing_keys_retire_at ON signing_keys (retire_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM signing_keys;
\ No newline at end of file
@@ -0,0 +1,90 @@
+package authverify
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the verified claims of an access token. UserID and SessionID are
+// empty for client_credentials tokens.
+type Claims struct {
+	Subject   string
+	UserID    string
+	SessionID string
+	ClientID  string
+	TokenID   string
+	Issuer    string
+	Audience  []string
+	Scopes    []string
+	Roles     []string
+	AMR       []string
+	AuthTime  time.Time
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+func newClaims(mapClaims jwt.MapClaims) *Claims {
+
+	claims := &Claims{
+		Roles: stringSlice(mapClaims["roles"]),
+		AMR:   stringSlice(mapClaims["amr"]),
+	}
+	claims.Subject, _ = mapClaims.GetSubject()
+	claims.Issuer, _ = mapClaims.GetIssuer()
+	claims.Audience, _ = mapClaims.GetAudience()
+	claims.UserID, _ = mapClaims["uid"].(string)
+	claims.SessionID, _ = mapClaims["sid"].(string)
+	claims.ClientID, _ = mapClaims["client_id"].(string)
+	claims.TokenID, _ = mapClaims["jti"].(string)
+	if scope, ok := mapClaims["scope"].(string); ok {
+		claims.Scopes = strings.Fields(scope)
+	}
+	if authTime, ok := mapClaims["auth_time"].(float64); ok {
+		claims.AuthTime = time.Unix(int64(authTime), 0)
+	}
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+
+	return claims
+}
+
+func stringSlice(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+type claimsKey struct{}
+
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
@@ -0,0 +1,106 @@
+// Package grpcauth adapts authverify to gRPC servers.
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpcauth.UnaryServerInterceptor(verifier)),
+//		grpc.ChainStreamInterceptor(grpcauth.StreamServerInterceptor(verifier)),
+//	)
+package grpcauth
+
+import (
+	"authservice/pkg/authverify"
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type SkipFunc func(fullMethod string) bool
+
+func UnaryServerInterceptor(verifier *authverify.Verifier, skip ...SkipFunc) grpc.UnaryServerInterceptor {
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if skipped(info.FullMethod, skip) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(verifier *authverify.Verifier, skip ...SkipFunc) grpc.StreamServerInterceptor {
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if skipped(info.FullMethod, skip) {
+			return handler(srv, stream)
+		}
+
+		ctx, err := authenticate(stream.Context(), verifier)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func BearerToken(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func authenticate(ctx context.Context, verifier *authverify.Verifier) (context.Context, error) {
+
+	claims, err := verifier.Verify(ctx, BearerToken(ctx))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return authverify.WithClaims(ctx, claims), nil
+}
+
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, authverify.ErrMissingToken):
+		return status.Error(codes.Unauthenticated, "access token required")
+	case errors.Is(err, authverify.ErrInvalidToken), errors.Is(err, authverify.ErrRevokedToken):
+		return status.Error(codes.Unauthenticated, "access token is invalid, expired or revoked")
+	default:
+		return status.Error(codes.Unavailable, "access token could not be verified")
+	}
+}
+
+func skipped(fullMethod string, skip []SkipFunc) bool {
+	for _, fn := range skip {
+		if fn(fullMethod) {
+			return true
+		}
+	}
+	return false
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,96 @@
+package authverify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims, err := v.Verify(r.Context(), BearerToken(r))
+		if err != nil {
+			v.config.ErrorHandler(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return require("insufficient_scope", func(claims *Claims) bool {
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return require("access_denied", func(claims *Claims) bool {
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func require(code string, allowed func(claims *Claims) bool) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			claims, ok := FromContext(r.Context())
+			if !ok {
+				WriteError(w, r, ErrMissingToken)
+				return
+			}
+			if !allowed(claims) {
+				writeJSONError(w, http.StatusForbidden, code, "access token does not grant this request")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		writeJSONError(w, http.StatusUnauthorized, "invalid_request", "access token required")
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrRevokedToken):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSONError(w, http.StatusUnauthorized, "invalid_token", "access token is invalid, expired or revoked")
+	default:
+		writeJSONError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "access token could not be verified")
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
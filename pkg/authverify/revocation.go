@@ -0,0 +1,38 @@
+package authverify
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *Claims) (bool, error)
+}
+
+type RedisRevocationChecker struct {
+	Client redis.UniversalClient
+}
+
+func NewRedisRevocationChecker(client redis.UniversalClient) *RedisRevocationChecker {
+	return &RedisRevocationChecker{
+		Client: client,
+	}
+}
+
+func (c *RedisRevocationChecker) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+
+	key := claims.SessionID
+	if key == "" {
+		key = claims.TokenID
+	}
+	if key == "" {
+		return false, nil
+	}
+
+	exists, err := c.Client.Exists(ctx, "bl:"+key).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
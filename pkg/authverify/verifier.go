@@ -0,0 +1,200 @@
+// Package authverify verifies access tokens issued by the auth service in
+// other Go services without calling it on every request.
+//
+//	verifier, err := authverify.NewVerifier(ctx, authverify.Config{
+//		JWKSURL:  "https://auth.example.com/.well-known/jwks.json",
+//		Issuer:   "https://auth.example.com",
+//		Audience: "https://billing.example.com",
+//	})
+//	router.Use(verifier.Middleware)
+package authverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMissingToken = errors.New("authverify: access token required")
+	ErrInvalidToken = errors.New("authverify: invalid access token")
+	ErrRevokedToken = errors.New("authverify: access token revoked")
+)
+
+const (
+	defaultRefreshInterval    = 5 * time.Minute
+	defaultMinRefreshInterval = 10 * time.Second
+)
+
+// signingMethods are the asymmetric algorithms the auth service signs with.
+// Symmetric keys are never published, so HS* tokens cannot be verified here.
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Config requires JWKSURL, Issuer and Audience, so that tokens of other
+// issuers or meant for other services are rejected.
+type Config struct {
+	JWKSURL  string
+	Issuer   string
+	Audience string
+	// SkipAudienceCheck accepts tokens for any audience instead of Audience.
+	// Only for services that check the aud claim themselves.
+	SkipAudienceCheck bool
+
+	// Zero means five minutes.
+	RefreshInterval time.Duration
+	// Zero means ten seconds.
+	MinRefreshInterval time.Duration
+	Leeway             time.Duration
+	// Nil means a client with a ten second timeout.
+	HTTPClient *http.Client
+	Revocation RevocationChecker
+	// Nil means WriteError.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type Verifier struct {
+	config Config
+	keys   *keySet
+	parser *jwt.Parser
+
+	stop     context.CancelFunc
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewVerifier(ctx context.Context, config Config) (*Verifier, error) {
+
+	if config.JWKSURL == "" {
+		return nil, errors.New("authverify: JWKSURL is required")
+	}
+	if config.Issuer == "" {
+		return nil, errors.New("authverify: Issuer is required")
+	}
+	if config.Audience == "" && !config.SkipAudienceCheck {
+		return nil, errors.New("authverify: Audience is required unless SkipAudienceCheck is set")
+	}
+	if config.Audience != "" && config.SkipAudienceCheck {
+		return nil, errors.New("authverify: Audience and SkipAudienceCheck are mutually exclusive")
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultRefreshInterval
+	}
+	if config.MinRefreshInterval <= 0 {
+		config.MinRefreshInterval = defaultMinRefreshInterval
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = WriteError
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(config.Leeway),
+		jwt.WithIssuer(config.Issuer),
+	}
+	if !config.SkipAudienceCheck {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	keys := newKeySet(config.JWKSURL, config.HTTPClient, config.MinRefreshInterval)
+	if err := keys.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	verifier := &Verifier{
+		config: config,
+		keys:   keys,
+		parser: jwt.NewParser(options...),
+		stop:   stop,
+		done:   make(chan struct{}),
+	}
+	go verifier.refreshLoop(ctx)
+
+	return verifier, nil
+}
+
+func (v *Verifier) Close() {
+	v.stopOnce.Do(func() {
+		v.stop()
+		<-v.done
+	})
+}
+
+func (v *Verifier) refreshLoop(ctx context.Context) {
+
+	defer close(v.done)
+
+	ticker := time.NewTicker(v.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// A failed fetch keeps the cached keys, so a short outage of
+			// the auth service does not reject valid tokens.
+			_ = v.keys.refresh(ctx)
+		}
+	}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	parsed, err := v.parser.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.keys.lookup(ctx, kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if key.Alg != "" && key.Alg != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.PublicKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+	// MFA and ID tokens carry token_use and are never access tokens.
+	if mapClaims["token_use"] != nil {
+		return nil, fmt.Errorf("%w: not an access token", ErrInvalidToken)
+	}
+
+	claims := newClaims(mapClaims)
+
+	if v.config.Revocation != nil {
+		revoked, err := v.config.Revocation.IsRevoked(ctx, claims)
+		if err != nil {
+			return nil, fmt.Errorf("authverify: check revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	return claims, nil
+}
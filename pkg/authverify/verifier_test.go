@@ -0,0 +1,395 @@
+package authverify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "https://billing.example.com"
+)
+
+// jwksServer publishes the public keys of locally generated ES256 keys and
+// counts how often they were fetched.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*ecdsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: map[string]*ecdsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveJWKS))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *jwksServer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+
+	s.fetches.Add(1)
+
+	s.mu.Lock()
+	keys := make([]jwk, 0, len(s.keys))
+	for kid, key := range s.keys {
+		keys = append(keys, jwk{
+			Kty: "EC",
+			Use: "sig",
+			Kid: kid,
+			Alg: "ES256",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+// publish generates a key and adds it to the JWKS.
+func (s *jwksServer) publish(t *testing.T, kid string) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key := newKey(t)
+	s.mu.Lock()
+	s.keys[kid] = key
+	s.mu.Unlock()
+
+	return key
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func newTestVerifier(t *testing.T, server *jwksServer, config Config) *Verifier {
+	t.Helper()
+
+	config.JWKSURL = server.URL
+	if config.Issuer == "" {
+		config.Issuer = testIssuer
+	}
+	if config.Audience == "" && !config.SkipAudienceCheck {
+		config.Audience = testAudience
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = time.Hour
+	}
+
+	verifier, err := NewVerifier(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	t.Cleanup(verifier.Close)
+
+	return verifier
+}
+
+// accessClaims are the claims of a user access token for testAudience.
+func accessClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   []string{testAudience},
+		"sub":   "user-1",
+		"uid":   "user-1",
+		"sid":   "session-1",
+		"jti":   "token-1",
+		"scope": "openid invoices:read",
+		"roles": []string{"admin"},
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func signToken(t *testing.T, kid string, key *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestNewVerifierRequiresIssuerAndAudience(t *testing.T) {
+
+	server := newJWKSServer(t)
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:    "missing issuer",
+			config:  Config{JWKSURL: server.URL, Audience: testAudience},
+			wantErr: true,
+		},
+		{
+			name:    "missing audience",
+			config:  Config{JWKSURL: server.URL, Issuer: testIssuer},
+			wantErr: true,
+		},
+		{
+			name:    "audience with skip audience check",
+			config:  Config{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience, SkipAudienceCheck: true},
+			wantErr: true,
+		},
+		{
+			name:   "issuer and audience",
+			config: Config{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience},
+		},
+		{
+			name:   "skip audience check",
+			config: Config{JWKSURL: server.URL, Issuer: testIssuer, SkipAudienceCheck: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			verifier, err := NewVerifier(context.Background(), tt.config)
+			if tt.wantErr {
+				if err == nil {
+					verifier.Close()
+					t.Fatal("NewVerifier succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewVerifier: %v", err)
+			}
+			verifier.Close()
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+
+	server := newJWKSServer(t)
+	key := server.publish(t, "key-1")
+	verifier := newTestVerifier(t, server, Config{})
+
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := accessClaims()
+		claims[name] = value
+		return claims
+	}
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims()).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign HMAC token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "valid token",
+			token: signToken(t, "key-1", key, accessClaims()),
+		},
+		{
+			name:    "missing token",
+			token:   "",
+			wantErr: ErrMissingToken,
+		},
+		{
+			name:    "wrong issuer",
+			token:   signToken(t, "key-1", key, withClaim("iss", "https://evil.example.com")),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "wrong audience",
+			token:   signToken(t, "key-1", key, withClaim("aud", []string{"https://admin.example.com"})),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "token_use claim",
+			token:   signToken(t, "key-1", key, withClaim("token_use", "mfa")),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "expired",
+			token:   signToken(t, "key-1", key, withClaim("exp", time.Now().Add(-time.Minute).Unix())),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "signed with unpublished key",
+			token:   signToken(t, "key-1", newKey(t), accessClaims()),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "symmetric algorithm",
+			token:   hmacToken,
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			if claims.UserID != "user-1" || claims.SessionID != "session-1" {
+				t.Errorf("claims = %+v, want user-1 and session-1", claims)
+			}
+			if !claims.HasScope("invoices:read") || !claims.HasRole("admin") {
+				t.Errorf("claims = %+v, want scope invoices:read and role admin", claims)
+			}
+		})
+	}
+}
+
+func TestVerifySkipAudienceCheck(t *testing.T) {
+
+	server := newJWKSServer(t)
+	key := server.publish(t, "key-1")
+	verifier := newTestVerifier(t, server, Config{SkipAudienceCheck: true})
+
+	claims := accessClaims()
+	claims["aud"] = []string{"https://admin.example.com"}
+
+	if _, err := verifier.Verify(context.Background(), signToken(t, "key-1", key, claims)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestVerifyRefetchesUnknownKeyID(t *testing.T) {
+
+	server := newJWKSServer(t)
+	server.publish(t, "key-1")
+
+	const minRefresh = 100 * time.Millisecond
+	verifier := newTestVerifier(t, server, Config{MinRefreshInterval: minRefresh})
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Fatalf("fetches after NewVerifier = %d, want 1", fetches)
+	}
+
+	// The auth service rotated its key right after the last fetch.
+	rotated := signToken(t, "key-2", server.publish(t, "key-2"), accessClaims())
+
+	if _, err := verifier.Verify(context.Background(), rotated); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify within MinRefreshInterval error = %v, want %v", err, ErrInvalidToken)
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Fatalf("fetches within MinRefreshInterval = %d, want 1", fetches)
+	}
+
+	time.Sleep(minRefresh)
+
+	if _, err := verifier.Verify(context.Background(), rotated); err != nil {
+		t.Fatalf("Verify after MinRefreshInterval: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Fatalf("fetches after unknown kid = %d, want 2", fetches)
+	}
+
+	// Tokens with kids that are never published do not cause a fetch each.
+	unknown := signToken(t, "key-3", newKey(t), accessClaims())
+	for range 3 {
+		if _, err := verifier.Verify(context.Background(), unknown); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("Verify unknown kid error = %v, want %v", err, ErrInvalidToken)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Fatalf("fetches after repeated unknown kid = %d, want 2", fetches)
+	}
+}
+
+func TestVerifyRevocation(t *testing.T) {
+
+	server := newJWKSServer(t)
+	key := server.publish(t, "key-1")
+
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	verifier := newTestVerifier(t, server, Config{Revocation: NewRedisRevocationChecker(redisClient)})
+
+	redisServer.Set("bl:revoked-session", "1")
+	redisServer.Set("bl:revoked-token", "1")
+
+	revokedSession := accessClaims()
+	revokedSession["sid"] = "revoked-session"
+
+	// client_credentials tokens have no session and are revoked by jti.
+	revokedClientToken := accessClaims()
+	delete(revokedClientToken, "uid")
+	delete(revokedClientToken, "sid")
+	revokedClientToken["jti"] = "revoked-token"
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr error
+	}{
+		{
+			name:   "not revoked",
+			claims: accessClaims(),
+		},
+		{
+			name:    "revoked session",
+			claims:  revokedSession,
+			wantErr: ErrRevokedToken,
+		},
+		{
+			name:    "revoked client token",
+			claims:  revokedClientToken,
+			wantErr: ErrRevokedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			_, err := verifier.Verify(context.Background(), signToken(t, "key-1", key, tt.claims))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}